@@ -0,0 +1,246 @@
+package fuzzy
+
+// AlignmentScoring configures the scores used by SmithWaterman.
+// Gap penalties are positive and subtracted from the score: a gap of length L
+// costs GapOpen + (L-1)*GapExtend, so setting both to the same value gives a
+// linear gap model.
+type AlignmentScoring struct {
+	Match     int // Score for aligning two equal bytes
+	Mismatch  int // Score for aligning two different bytes, usually negative
+	GapOpen   int // Penalty for the first byte of a gap
+	GapExtend int // Penalty for every further byte of a gap
+}
+
+// DefaultAlignmentScoring returns a scoring that favours short, dense local
+// alignments of text
+func DefaultAlignmentScoring() AlignmentScoring {
+	return AlignmentScoring{
+		Match:     2,
+		Mismatch:  -1,
+		GapOpen:   3,
+		GapExtend: 1,
+	}
+}
+
+// Alignment describes how two strings align. Start and End are byte offsets
+// of the aligned regions, s1[Start1:End1] and s2[Start2:End2]. Aligned1 and
+// Aligned2 are those regions with '-' inserted where the other string has a
+// gap, so both have the same length.
+type Alignment struct {
+	Score    int
+	Start1   int
+	End1     int
+	Start2   int
+	End2     int
+	Aligned1 string
+	Aligned2 string
+}
+
+// alignment traceback states
+const (
+	alignDiag = iota
+	alignGap1 // gap in s1, consuming s2
+	alignGap2 // gap in s2, consuming s1
+)
+
+// negInf is low enough to never win a max while leaving room for penalties
+const negInf = -(1 << 30)
+
+// SmithWaterman computes the best local alignment of s1 and s2 using the
+// Smith-Waterman algorithm with Gotoh's affine gaps. It is useful for finding
+// a short label inside a long noisy description, where the unaligned parts of
+// either string cost nothing. Strings are compared byte by byte.
+func SmithWaterman(s1, s2 string, scoring AlignmentScoring) Alignment {
+	n := len(s1)
+	m := len(s2)
+	if n == 0 || m == 0 {
+		return Alignment{}
+	}
+
+	// h holds the best score of an alignment ending at (i, j), e of one
+	// ending with a gap in s1 and f of one ending with a gap in s2
+	width := m + 1
+	h := make([]int, (n+1)*width)
+	e := make([]int, (n+1)*width)
+	f := make([]int, (n+1)*width)
+	for k := range e {
+		e[k] = negInf
+		f[k] = negInf
+	}
+
+	best, bestI, bestJ := 0, 0, 0
+	for i := 1; i <= n; i++ {
+		row := i * width
+		prevRow := row - width
+		for j := 1; j <= m; j++ {
+			e[row+j] = maxInt(e[row+j-1]-scoring.GapExtend, h[row+j-1]-scoring.GapOpen)
+			f[row+j] = maxInt(f[prevRow+j]-scoring.GapExtend, h[prevRow+j]-scoring.GapOpen)
+
+			score := h[prevRow+j-1] + substitutionScore(s1[i-1], s2[j-1], scoring)
+			score = maxInt(maxInt(score, 0), maxInt(e[row+j], f[row+j]))
+			h[row+j] = score
+
+			if score > best {
+				best, bestI, bestJ = score, i, j
+			}
+		}
+	}
+
+	if best == 0 {
+		return Alignment{}
+	}
+
+	aligned1 := make([]byte, 0, bestI+bestJ)
+	aligned2 := make([]byte, 0, bestI+bestJ)
+	i, j := bestI, bestJ
+	state := alignDiag
+
+	// A local alignment starts where the score drops back to zero
+	for i > 0 && j > 0 && !(state == alignDiag && h[i*width+j] == 0) {
+		k := i*width + j
+		switch state {
+		case alignDiag:
+			if h[k] == h[k-width-1]+substitutionScore(s1[i-1], s2[j-1], scoring) {
+				aligned1 = append(aligned1, s1[i-1])
+				aligned2 = append(aligned2, s2[j-1])
+				i--
+				j--
+			} else if h[k] == e[k] {
+				state = alignGap1
+			} else {
+				state = alignGap2
+			}
+		case alignGap1:
+			aligned1 = append(aligned1, '-')
+			aligned2 = append(aligned2, s2[j-1])
+			if e[k] == h[k-1]-scoring.GapOpen {
+				state = alignDiag
+			}
+			j--
+		case alignGap2:
+			aligned1 = append(aligned1, s1[i-1])
+			aligned2 = append(aligned2, '-')
+			if f[k] == h[k-width]-scoring.GapOpen {
+				state = alignDiag
+			}
+			i--
+		}
+	}
+
+	reverseBytes(aligned1)
+	reverseBytes(aligned2)
+
+	return Alignment{
+		Score:    best,
+		Start1:   i,
+		End1:     bestI,
+		Start2:   j,
+		End2:     bestJ,
+		Aligned1: string(aligned1),
+		Aligned2: string(aligned2),
+	}
+}
+
+// NeedlemanWunsch computes the best global alignment of s1 and s2 using the
+// Needleman-Wunsch algorithm with Gotoh's affine gaps. Aligned bytes are
+// scored with matrix, and gaps follow the same convention as
+// AlignmentScoring: a gap of length L costs gapOpen + (L-1)*gapExtend.
+func NeedlemanWunsch(s1, s2 string, matrix *SubstitutionMatrix, gapOpen, gapExtend int) Alignment {
+	n := len(s1)
+	m := len(s2)
+
+	width := m + 1
+	h := make([]int, (n+1)*width)
+	e := make([]int, (n+1)*width)
+	f := make([]int, (n+1)*width)
+	for k := range e {
+		e[k] = negInf
+		f[k] = negInf
+	}
+
+	// Leading gaps are penalized like any other gap
+	for i := 1; i <= n; i++ {
+		f[i*width] = -gapOpen - (i-1)*gapExtend
+		h[i*width] = f[i*width]
+	}
+	for j := 1; j <= m; j++ {
+		e[j] = -gapOpen - (j-1)*gapExtend
+		h[j] = e[j]
+	}
+
+	for i := 1; i <= n; i++ {
+		row := i * width
+		prevRow := row - width
+		for j := 1; j <= m; j++ {
+			e[row+j] = maxInt(e[row+j-1]-gapExtend, h[row+j-1]-gapOpen)
+			f[row+j] = maxInt(f[prevRow+j]-gapExtend, h[prevRow+j]-gapOpen)
+
+			score := h[prevRow+j-1] + matrix.Score(s1[i-1], s2[j-1])
+			h[row+j] = maxInt(score, maxInt(e[row+j], f[row+j]))
+		}
+	}
+
+	aligned1 := make([]byte, 0, n+m)
+	aligned2 := make([]byte, 0, n+m)
+	i, j := n, m
+	state := alignDiag
+
+	for i > 0 || j > 0 {
+		k := i*width + j
+		switch {
+		case state == alignDiag && i == 0:
+			state = alignGap1
+		case state == alignDiag && j == 0:
+			state = alignGap2
+		case state == alignDiag:
+			if h[k] == h[k-width-1]+matrix.Score(s1[i-1], s2[j-1]) {
+				aligned1 = append(aligned1, s1[i-1])
+				aligned2 = append(aligned2, s2[j-1])
+				i--
+				j--
+			} else if h[k] == e[k] {
+				state = alignGap1
+			} else {
+				state = alignGap2
+			}
+		case state == alignGap1:
+			aligned1 = append(aligned1, '-')
+			aligned2 = append(aligned2, s2[j-1])
+			if e[k] == h[k-1]-gapOpen {
+				state = alignDiag
+			}
+			j--
+		default:
+			aligned1 = append(aligned1, s1[i-1])
+			aligned2 = append(aligned2, '-')
+			if f[k] == h[k-width]-gapOpen {
+				state = alignDiag
+			}
+			i--
+		}
+	}
+
+	reverseBytes(aligned1)
+	reverseBytes(aligned2)
+
+	return Alignment{
+		Score:    h[n*width+m],
+		End1:     n,
+		End2:     m,
+		Aligned1: string(aligned1),
+		Aligned2: string(aligned2),
+	}
+}
+
+func substitutionScore(a, b byte, scoring AlignmentScoring) int {
+	if a == b {
+		return scoring.Match
+	}
+	return scoring.Mismatch
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
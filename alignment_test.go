@@ -0,0 +1,172 @@
+package fuzzy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSmithWaterman(t *testing.T) {
+	// Classic example with linear gaps
+	scoring := AlignmentScoring{Match: 3, Mismatch: -3, GapOpen: 2, GapExtend: 2}
+	got := SmithWaterman("TGTTACGG", "GGTTGACTA", scoring)
+
+	if got.Score != 13 {
+		t.Errorf("Score = %d, want 13", got.Score)
+	}
+	if got.Aligned1 != "GTT-AC" || got.Aligned2 != "GTTGAC" {
+		t.Errorf("alignment = %q / %q, want %q / %q", got.Aligned1, got.Aligned2, "GTT-AC", "GTTGAC")
+	}
+	if got.Start1 != 1 || got.End1 != 6 || got.Start2 != 1 || got.End2 != 7 {
+		t.Errorf("regions = [%d:%d] [%d:%d], want [1:6] [1:7]", got.Start1, got.End1, got.Start2, got.End2)
+	}
+}
+
+func TestSmithWatermanLabelInDescription(t *testing.T) {
+	label := "connection refused"
+	description := "2024-01-01 ERROR upstream conection  refusd by peer, retrying"
+
+	got := SmithWaterman(label, description, DefaultAlignmentScoring())
+	region := description[got.Start2:got.End2]
+	if region != "conection  refus" {
+		t.Errorf("aligned region = %q, want %q", region, "conection  refus")
+	}
+	if len(got.Aligned1) != len(got.Aligned2) {
+		t.Errorf("aligned strings differ in length: %q / %q", got.Aligned1, got.Aligned2)
+	}
+}
+
+func TestSmithWatermanAffineGaps(t *testing.T) {
+	// With a cheap extension one long gap beats several short ones
+	affine := AlignmentScoring{Match: 2, Mismatch: -2, GapOpen: 4, GapExtend: 1}
+	got := SmithWaterman("ABCDEFGHIJ", "ABCDXXXXEFGHIJ", affine)
+
+	if got.Aligned1 != "ABCD----EFGHIJ" {
+		t.Errorf("Aligned1 = %q, want %q", got.Aligned1, "ABCD----EFGHIJ")
+	}
+	if want := 20 - 4 - 3; got.Score != want {
+		t.Errorf("Score = %d, want %d", got.Score, want)
+	}
+}
+
+func TestSmithWatermanNoMatch(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+	}{
+		{"", ""},
+		{"abc", ""},
+		{"abc", "xyz"},
+	}
+
+	for _, tt := range tests {
+		got := SmithWaterman(tt.s1, tt.s2, DefaultAlignmentScoring())
+		if got != (Alignment{}) {
+			t.Errorf("SmithWaterman(%q, %q) = %+v, want empty alignment", tt.s1, tt.s2, got)
+		}
+	}
+}
+
+func BenchmarkSmithWaterman(b *testing.B) {
+	label := "connection refused"
+	description := "2024-01-01 ERROR upstream conection  refusd by peer, retrying in 5 seconds"
+	scoring := DefaultAlignmentScoring()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SmithWaterman(label, description, scoring)
+	}
+}
+
+func TestNeedlemanWunsch(t *testing.T) {
+	// Classic example with match 1, mismatch -1 and linear gap -1
+	got := NeedlemanWunsch("GATTACA", "GCATGCU", MatchMismatchMatrix(1, -1), 1, 1)
+
+	if got.Score != 0 {
+		t.Errorf("Score = %d, want 0", got.Score)
+	}
+	if len(got.Aligned1) != len(got.Aligned2) {
+		t.Fatalf("aligned strings differ in length: %q / %q", got.Aligned1, got.Aligned2)
+	}
+	if stripGaps(got.Aligned1) != "GATTACA" || stripGaps(got.Aligned2) != "GCATGCU" {
+		t.Errorf("alignment %q / %q does not cover both inputs", got.Aligned1, got.Aligned2)
+	}
+	if got.End1 != 7 || got.End2 != 7 {
+		t.Errorf("ends = %d, %d, want 7, 7", got.End1, got.End2)
+	}
+}
+
+func TestNeedlemanWunschBLOSUM62(t *testing.T) {
+	got := NeedlemanWunsch("HEAGAWGHEE", "PAWHEAE", BLOSUM62, 10, 1)
+	if got.Score != rescoreAlignment(got, BLOSUM62, 10, 1) {
+		t.Errorf("Score = %d does not match its alignment %q / %q", got.Score, got.Aligned1, got.Aligned2)
+	}
+
+	self := NeedlemanWunsch("WCW", "WCW", BLOSUM62, 10, 1)
+	if self.Score != 11+9+11 {
+		t.Errorf("self alignment Score = %d, want 31", self.Score)
+	}
+
+	// Lower case input scores like upper case
+	lower := NeedlemanWunsch("wcw", "WCW", BLOSUM62, 10, 1)
+	if lower.Score != self.Score {
+		t.Errorf("lower case Score = %d, want %d", lower.Score, self.Score)
+	}
+}
+
+func TestNeedlemanWunschAffineGaps(t *testing.T) {
+	matrix := MatchMismatchMatrix(2, -2)
+	got := NeedlemanWunsch("ABCDEFGHIJ", "ABCDXXXXEFGHIJ", matrix, 4, 1)
+
+	if got.Aligned1 != "ABCD----EFGHIJ" {
+		t.Errorf("Aligned1 = %q, want %q", got.Aligned1, "ABCD----EFGHIJ")
+	}
+	if got.Score != rescoreAlignment(got, matrix, 4, 1) {
+		t.Errorf("Score = %d does not match its alignment", got.Score)
+	}
+
+	empty := NeedlemanWunsch("", "ABC", matrix, 4, 1)
+	if empty.Score != -6 || empty.Aligned1 != "---" || empty.Aligned2 != "ABC" {
+		t.Errorf("NeedlemanWunsch(\"\", \"ABC\") = %+v", empty)
+	}
+}
+
+func stripGaps(s string) string {
+	return strings.ReplaceAll(s, "-", "")
+}
+
+// rescoreAlignment recomputes the score of an alignment from its aligned strings
+func rescoreAlignment(a Alignment, matrix *SubstitutionMatrix, gapOpen, gapExtend int) int {
+	score := 0
+	gap1, gap2 := false, false
+	for i := 0; i < len(a.Aligned1); i++ {
+		switch {
+		case a.Aligned1[i] == '-':
+			if gap1 {
+				score -= gapExtend
+			} else {
+				score -= gapOpen
+			}
+			gap1, gap2 = true, false
+		case a.Aligned2[i] == '-':
+			if gap2 {
+				score -= gapExtend
+			} else {
+				score -= gapOpen
+			}
+			gap1, gap2 = false, true
+		default:
+			score += matrix.Score(a.Aligned1[i], a.Aligned2[i])
+			gap1, gap2 = false, false
+		}
+	}
+	return score
+}
+
+func BenchmarkNeedlemanWunsch(b *testing.B) {
+	s1 := "MKTAYIAKQRQISFVKSHFSRQLEERLGLIEVQAPILSRVGDGTQDNLSGAEKAVQVKVKALPDAQ"
+	s2 := "MKTAYIAKQRQISFVKSHFSRQLEERLGLIEVQAPILSRVGDGTQDNLSGAEKAVQVKVKALPDAQFEVVHSLAKWKRQTLGQHDFSAGEGLYTHMKALRPDEDRLSPLHSVYVDQWDWERVMGDGERQFSTLKSTVEAIWAGIKATEAAVSEEFGLAPFLPDQIHFVHSQELLSRYPDLDAKGRERAIAKDLGAVFLVGIGGKLSDGHRHDVRAPDYDDWUAXGVANYLR"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NeedlemanWunsch(s1, s2, BLOSUM62, 11, 1)
+	}
+}
@@ -3,6 +3,7 @@ package benchmark
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
 	"testing"
@@ -126,4 +127,91 @@ func BenchmarkStreamSearch10GB(b *testing.B) {
 		
 		file.Close()
 	}
-}
\ No newline at end of file
+}
+
+// loadPrefix reads the first size bytes of filename
+func loadPrefix(filename string, size int) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buf := make([]byte, size)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return string(buf[:n]), nil
+}
+
+func BenchmarkSuffixArray10GB(b *testing.B) {
+	if _, err := os.Stat("testdata/10gb_words.txt"); os.IsNotExist(err) {
+		b.Skip("10GB test file not found. Run: go run generate_10gb.go")
+	}
+
+	for _, size := range []int{1 << 20, 16 << 20, 64 << 20} {
+		text, err := loadPrefix("testdata/10gb_words.txt", size)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("%dMB", len(text)>>20), func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = raphamorim.NewSuffixArray(text)
+			}
+		})
+	}
+}
+
+
+func BenchmarkFMIndex10GB(b *testing.B) {
+	if _, err := os.Stat("testdata/10gb_words.txt"); os.IsNotExist(err) {
+		b.Skip("10GB test file not found. Run: go run generate_10gb.go")
+	}
+
+	text, err := loadPrefix("testdata/10gb_words.txt", 64<<20)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	fmt.Printf("Building FM-index over %d MB...\n", len(text)>>20)
+	startTime := time.Now()
+	fm := raphamorim.NewFMIndex(text, 32)
+	fmt.Printf("FM-index built in %v\n", time.Since(startTime))
+
+	queries := []string{"algorithm", "database", "network", "security", "performance"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query := queries[i%len(queries)]
+		_ = fm.Count(query)
+	}
+}
+
+func BenchmarkBuildSuffixArrayFile10GB(b *testing.B) {
+	if _, err := os.Stat("testdata/10gb_words.txt"); os.IsNotExist(err) {
+		b.Skip("10GB test file not found. Run: go run generate_10gb.go")
+	}
+
+	const size = 1 << 30
+	path := b.TempDir() + "/words.sa"
+	opts := raphamorim.ExternalBuildOptions{MemoryBudget: 256 << 20, WorkDir: b.TempDir()}
+
+	b.SetBytes(size)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file, err := os.Open("testdata/10gb_words.txt")
+		if err != nil {
+			b.Fatal(err)
+		}
+		err = raphamorim.BuildSuffixArrayFile(io.LimitReader(file, size), path, opts)
+		file.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -1,91 +1,97 @@
 package fuzzy
 
+import (
+	"fmt"
+	"unsafe"
+)
+
 // BKTree is a metric tree data structure for fast similarity search
 type BKTree struct {
-	root     *BKNode
+	tree     bkTree[string]
 	distance DistanceFunc
-}
 
-// BKNode represents a node in the BK-tree
-type BKNode struct {
-	word     string
-	children []childNode
-}
+	// levenshtein is set when distance is LevenshteinDistance, allowing
+	// searches to compile the query once
+	levenshtein bool
 
-type childNode struct {
-	distance int
-	node     *BKNode
+	// metric is the registered name of distance, empty for custom functions
+	metric string
 }
 
-// DistanceFunc is a function that calculates distance between two strings
-type DistanceFunc func(s1, s2 string) int
+// BKNode represents a node in the BK-tree
+type BKNode = bkNode[string]
+
+// bkTree is the BK-tree behind BKTree and SequenceBKTree, holding items of
+// any type. The distance is passed to each call, so wrappers can specialize
+// it per query.
+type bkTree[T any] struct {
+	root *bkNode[T]
+	size int
+}
 
-// NewBKTree creates a new BK-tree with the default Levenshtein distance
-func NewBKTree() *BKTree {
-	return &BKTree{
-		distance: LevenshteinDistance,
-	}
+type bkNode[T any] struct {
+	item     T
+	children []bkChild[T]
 }
 
-// NewBKTreeWithDistance creates a new BK-tree with a custom distance function
-func NewBKTreeWithDistance(distFunc DistanceFunc) *BKTree {
-	return &BKTree{
-		distance: distFunc,
-	}
+type bkChild[T any] struct {
+	distance int
+	node     *bkNode[T]
 }
 
-// Add inserts a word into the BK-tree
-func (t *BKTree) Add(word string) {
+// add inserts item unless an item at distance 0 is already present
+func (t *bkTree[T]) add(item T, distance func(a, b T) int) {
 	if t.root == nil {
-		t.root = &BKNode{word: word}
+		t.root = &bkNode[T]{item: item}
+		t.size++
 		return
 	}
 
 	node := t.root
 	for {
-		dist := t.distance(node.word, word)
+		dist := distance(node.item, item)
 		if dist == 0 {
-			return // Word already exists
+			return // Item already exists
 		}
 
 		// Find child with matching distance
-		found := false
+		var next *bkNode[T]
 		for _, child := range node.children {
 			if child.distance == dist {
-				node = child.node
-				found = true
+				next = child.node
 				break
 			}
 		}
 
-		if !found {
+		if next == nil {
 			// Add new child
-			node.children = append(node.children, childNode{
+			node.children = append(node.children, bkChild[T]{
 				distance: dist,
-				node:     &BKNode{word: word},
+				node:     &bkNode[T]{item: item},
 			})
+			t.size++
 			return
 		}
+		node = next
 	}
 }
 
-// Search finds all words within maxDistance edits of the query
-func (t *BKTree) Search(query string, maxDistance int) []string {
+// search calls found for every item whose distance to the query, measured
+// by distance, is at most maxDistance
+func (t *bkTree[T]) search(distance func(item T) int, maxDistance int, found func(item T, dist int)) {
 	if t.root == nil {
-		return nil
+		return
 	}
 
-	var results []string
-	candidates := []*BKNode{t.root}
-
+	candidates := []*bkNode[T]{t.root}
 	for len(candidates) > 0 {
 		// Pop from stack
 		node := candidates[len(candidates)-1]
 		candidates = candidates[:len(candidates)-1]
 
-		dist := t.distance(node.word, query)
+		dist := distance(node.item)
 		if dist <= maxDistance {
-			results = append(results, node.word)
+			found(node.item, dist)
 		}
 
 		// Calculate search bounds
@@ -99,173 +105,151 @@ func (t *BKTree) Search(query string, maxDistance int) []string {
 			}
 		}
 	}
+}
 
-	return results
+// DistanceFunc is a function that calculates distance between two strings
+type DistanceFunc func(s1, s2 string) int
+
+// NewBKTree creates a new BK-tree with the default Levenshtein distance
+func NewBKTree() *BKTree {
+	return &BKTree{
+		distance:    LevenshteinDistance,
+		levenshtein: true,
+		metric:      "levenshtein",
+	}
 }
 
-// SearchWithScores returns words with their distances
-func (t *BKTree) SearchWithScores(query string, maxDistance int) []SearchResult {
-	if t.root == nil {
-		return nil
+// NewBKTreeWithDistance creates a new BK-tree with a custom distance function
+func NewBKTreeWithDistance(distFunc DistanceFunc) *BKTree {
+	return &BKTree{
+		distance: distFunc,
 	}
+}
 
-	var results []SearchResult
-	candidates := []*BKNode{t.root}
+// NewBKTreeWithMetric creates a new BK-tree using a registered metric. It
+// fails for unknown names and for distances that are not true metrics,
+// since the tree's pruning relies on the triangle inequality.
+func NewBKTreeWithMetric(name string) (*BKTree, error) {
+	m, ok := LookupMetric(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", name)
+	}
+	if !m.IsMetric {
+		return nil, fmt.Errorf("%q is not a true metric and cannot be used with a BK-tree", name)
+	}
 
-	for len(candidates) > 0 {
-		// Pop from stack
-		node := candidates[len(candidates)-1]
-		candidates = candidates[:len(candidates)-1]
+	return &BKTree{
+		distance:    m.Distance,
+		levenshtein: name == "levenshtein",
+		metric:      name,
+	}, nil
+}
 
-		dist := t.distance(node.word, query)
-		if dist <= maxDistance {
-			results = append(results, SearchResult{
-				Word:     node.word,
-				Distance: dist,
-			})
-		}
+// Metric returns the registered name of the tree's distance, or an empty
+// string when it was built with a custom DistanceFunc
+func (t *BKTree) Metric() string {
+	return t.metric
+}
 
-		// Calculate search bounds
-		minDist := dist - maxDistance
-		maxDist := dist + maxDistance
+// Add inserts a word into the BK-tree
+func (t *BKTree) Add(word string) {
+	t.tree.add(word, t.distance)
+}
 
-		// Add children within bounds to candidates
-		for _, child := range node.children {
-			if child.distance >= minDist && child.distance <= maxDist {
-				candidates = append(candidates, child.node)
-			}
-		}
-	}
+// Search finds all words within maxDistance edits of the query
+func (t *BKTree) Search(query string, maxDistance int) []string {
+	var results []string
+	t.tree.search(t.queryDistance(query), maxDistance, func(word string, dist int) {
+		results = append(results, word)
+	})
+	return results
+}
 
+// SearchWithScores returns words with their distances
+func (t *BKTree) SearchWithScores(query string, maxDistance int) []SearchResult {
+	var results []SearchResult
+	t.tree.search(t.queryDistance(query), maxDistance, func(word string, dist int) {
+		results = append(results, SearchResult{
+			Word:     word,
+			Distance: dist,
+			Score:    normalizeDistance(dist, MaxLengthBound(word, query)),
+		})
+	})
 	return results
 }
 
-// SearchResult contains a word and its distance from the query
+// queryDistance returns a function measuring the distance from a word to
+// query, backed by a CompiledQuery for Levenshtein trees
+func (t *BKTree) queryDistance(query string) func(word string) int {
+	if t.levenshtein {
+		return CompileQuery(query).Distance
+	}
+	return func(word string) int {
+		return t.distance(word, query)
+	}
+}
+
+// SearchResult contains a word and its distance from the query.
+// Score normalizes Distance by the length of the longer string into [0, 1],
+// which equals LevenshteinSimilarity when the default distance is used.
 type SearchResult struct {
 	Word     string
 	Distance int
+	Score    float64
 }
 
 // Size returns the number of words in the tree
 func (t *BKTree) Size() int {
-	if t.root == nil {
-		return 0
-	}
-	return t.sizeNode(t.root)
+	return t.tree.size
 }
 
-func (t *BKTree) sizeNode(node *BKNode) int {
-	count := 1
-	for _, child := range node.children {
-		count += t.sizeNode(child.node)
-	}
-	return count
-}
-
-// Standard Levenshtein Distance (optimized with two-row approach)
+// Standard Levenshtein Distance (optimized with two-row approach).
+// Rows come from a pool of DistanceBuffer values, so calls do not allocate.
 func LevenshteinDistance(s1, s2 string) int {
-	if len(s1) == 0 {
-		return len(s2)
-	}
-	if len(s2) == 0 {
-		return len(s1)
-	}
-	if s1 == s2 {
-		return 0
-	}
-
-	// Work with bytes for ASCII strings (faster than runes)
-	b1 := []byte(s1)
-	b2 := []byte(s2)
-
-	// Make sure b1 is the shorter string
-	if len(b1) > len(b2) {
-		b1, b2 = b2, b1
-	}
-
-	prev := make([]int, len(b1)+1)
-	curr := make([]int, len(b1)+1)
-
-	// Initialize first row
-	for i := 0; i <= len(b1); i++ {
-		prev[i] = i
-	}
+	b := distanceBuffers.Get().(*DistanceBuffer)
+	dist := b.Levenshtein(s1, s2)
+	distanceBuffers.Put(b)
+	return dist
+}
 
-	// Fill matrix
-	for j := 1; j <= len(b2); j++ {
-		curr[0] = j
-		for i := 1; i <= len(b1); i++ {
-			cost := 0
-			if b1[i-1] != b2[j-1] {
-				cost = 1
-			}
-			curr[i] = min3(
-				prev[i]+1,      // deletion
-				curr[i-1]+1,    // insertion
-				prev[i-1]+cost, // substitution
-			)
-		}
-		prev, curr = curr, prev
-	}
+// DamerauLevenshteinDistance calculates the restricted Damerau-Levenshtein
+// distance, better known as the optimal string alignment (OSA) distance.
+// It is kept under this name for compatibility and is equivalent to
+// OSADistance. OSA does not satisfy the triangle inequality, so use
+// UnrestrictedDamerauLevenshteinDistance when building a BKTree.
+func DamerauLevenshteinDistance(s1, s2 string) int {
+	return OSADistance(s1, s2)
+}
 
-	return prev[len(b1)]
+// OSADistance calculates the optimal string alignment distance allowing
+// insertions, deletions, substitutions, and transpositions of adjacent
+// characters, where no substring may be edited more than once.
+// For example OSADistance("ca", "abc") is 3, because the transposed "ac"
+// cannot then have "b" inserted between its characters.
+func OSADistance(s1, s2 string) int {
+	b := distanceBuffers.Get().(*DistanceBuffer)
+	dist := b.OSA(s1, s2)
+	distanceBuffers.Put(b)
+	return dist
 }
 
-// DamerauLevenshteinDistance calculates the Damerau-Levenshtein distance
-// allowing insertions, deletions, substitutions, and transpositions
-func DamerauLevenshteinDistance(s1, s2 string) int {
+// UnrestrictedDamerauLevenshteinDistance calculates the true Damerau-Levenshtein
+// distance, Damerau over the bytes of the strings. Unlike OSADistance, edits
+// may be applied to already transposed characters, which makes it a metric
+// that is safe to use with BKTree.
+func UnrestrictedDamerauLevenshteinDistance(s1, s2 string) int {
 	if s1 == s2 {
 		return 0
 	}
+	// Bytes index the last row table directly, with no numbering or copies
+	var lastRow [256]int
+	return lowranceWagner(stringBytes(s1), stringBytes(s2), lastRow[:])
+}
 
-	len1 := len(s1)
-	len2 := len(s2)
-
-	if len1 == 0 {
-		return len2
-	}
-	if len2 == 0 {
-		return len1
-	}
-
-	// Create matrix
-	matrix := make([][]int, len1+1)
-	for i := range matrix {
-		matrix[i] = make([]int, len2+1)
-	}
-
-	// Initialize first row and column
-	for i := 0; i <= len1; i++ {
-		matrix[i][0] = i
-	}
-	for j := 0; j <= len2; j++ {
-		matrix[0][j] = j
-	}
-
-	// Fill matrix
-	for i := 1; i <= len1; i++ {
-		for j := 1; j <= len2; j++ {
-			cost := 0
-			if s1[i-1] != s2[j-1] {
-				cost = 1
-			}
-
-			matrix[i][j] = min3(
-				matrix[i-1][j]+1,      // deletion
-				matrix[i][j-1]+1,      // insertion
-				matrix[i-1][j-1]+cost, // substitution
-			)
-
-			// Transposition
-			if i > 1 && j > 1 &&
-				s1[i-1] == s2[j-2] &&
-				s1[i-2] == s2[j-1] {
-				matrix[i][j] = min(matrix[i][j], matrix[i-2][j-2]+cost)
-			}
-		}
-	}
-
-	return matrix[len1][len2]
+// stringBytes views the bytes of s without copying them; they must not be
+// modified
+func stringBytes(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
 }
 
 // Helper functions
@@ -70,6 +70,71 @@ func TestDamerauLevenshteinDistance(t *testing.T) {
 	}
 }
 
+func TestUnrestrictedDamerauLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"", "", 0},
+		{"a", "", 1},
+		{"", "a", 1},
+		{"abc", "abc", 0},
+		{"abc", "acb", 1}, // transposition
+		{"ca", "abc", 2},  // transposition then insertion, OSA gives 3
+		{"kitten", "sitting", 3},
+		{"abcdef", "badcfe", 3},
+	}
+	
+	for _, tt := range tests {
+		got := UnrestrictedDamerauLevenshteinDistance(tt.s1, tt.s2)
+		if got != tt.want {
+			t.Errorf("UnrestrictedDamerauLevenshteinDistance(%q, %q) = %d, want %d", tt.s1, tt.s2, got, tt.want)
+		}
+	}
+}
+
+func TestDamerauTriangleInequality(t *testing.T) {
+	// OSA violates the triangle inequality on this triple
+	if OSADistance("ca", "ac")+OSADistance("ac", "abc") >= OSADistance("ca", "abc") {
+		t.Error("expected OSADistance to violate the triangle inequality for ca/ac/abc")
+	}
+	
+	words := []string{"", "a", "ab", "ba", "abc", "acb", "ca", "ac", "bca", "cab", "abcd", "badc", "dcba"}
+	for _, a := range words {
+		for _, b := range words {
+			for _, c := range words {
+				ab := UnrestrictedDamerauLevenshteinDistance(a, b)
+				bc := UnrestrictedDamerauLevenshteinDistance(b, c)
+				ac := UnrestrictedDamerauLevenshteinDistance(a, c)
+				if ac > ab+bc {
+					t.Fatalf("triangle inequality violated: d(%q,%q)=%d > d(%q,%q)+d(%q,%q)=%d", a, c, ac, a, b, b, c, ab+bc)
+				}
+			}
+		}
+	}
+}
+
+func TestBKTreeUnrestrictedDamerau(t *testing.T) {
+	tree := NewBKTreeWithDistance(UnrestrictedDamerauLevenshteinDistance)
+	words := []string{"abc", "ca", "ac", "cab", "bca", "abcd"}
+	for _, word := range words {
+		tree.Add(word)
+	}
+	
+	for _, query := range words {
+		got := make(map[string]bool)
+		for _, w := range tree.Search(query, 2) {
+			got[w] = true
+		}
+		for _, w := range words {
+			want := UnrestrictedDamerauLevenshteinDistance(query, w) <= 2
+			if want != got[w] {
+				t.Errorf("Search(%q, 2): presence of %q = %v, want %v", query, w, got[w], want)
+			}
+		}
+	}
+}
+
 func TestMyersDistance(t *testing.T) {
 	tests := []struct {
 		s1, s2 string
@@ -95,6 +160,7 @@ func BenchmarkLevenshtein(b *testing.B) {
 	s1 := "The quick brown fox jumps over the lazy dog"
 	s2 := "The quick brown fox jumped over the lazy dogs"
 	
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		LevenshteinDistance(s1, s2)
@@ -105,12 +171,23 @@ func BenchmarkDamerauLevenshtein(b *testing.B) {
 	s1 := "The quick brown fox jumps over the lazy dog"
 	s2 := "The quick brown fox jumped over the lazy dogs"
 	
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		DamerauLevenshteinDistance(s1, s2)
 	}
 }
 
+func BenchmarkUnrestrictedDamerauLevenshtein(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		UnrestrictedDamerauLevenshteinDistance(s1, s2)
+	}
+}
+
 func BenchmarkMyers(b *testing.B) {
 	s1 := "The quick brown fox jumps over the lazy dog"
 	s2 := "The quick brown fox jumped over the lazy dogs"
@@ -0,0 +1,121 @@
+package fuzzy
+
+import "sync"
+
+// DistanceBuffer holds the rows reused by the edit distance computations so
+// repeated calls do not allocate. The zero value is ready to use and grows
+// to fit the longest input it has seen. A DistanceBuffer must not be shared
+// between goroutines.
+type DistanceBuffer struct {
+	rows []int
+}
+
+var distanceBuffers = sync.Pool{
+	New: func() any { return new(DistanceBuffer) },
+}
+
+// rowsFor returns count rows of n ints each, carved out of the buffer
+func (b *DistanceBuffer) rowsFor(count, n int) []int {
+	if cap(b.rows) < count*n {
+		b.rows = make([]int, count*n)
+	}
+	return b.rows[:count*n]
+}
+
+// Levenshtein returns LevenshteinDistance(s1, s2) using the buffer's rows
+func (b *DistanceBuffer) Levenshtein(s1, s2 string) int {
+	if len(s1) == 0 {
+		return len(s2)
+	}
+	if len(s2) == 0 {
+		return len(s1)
+	}
+	if s1 == s2 {
+		return 0
+	}
+
+	// Make sure s1 is the shorter string
+	if len(s1) > len(s2) {
+		s1, s2 = s2, s1
+	}
+
+	n := len(s1) + 1
+	rows := b.rowsFor(2, n)
+	prev, curr := rows[:n], rows[n:]
+
+	for i := range prev {
+		prev[i] = i
+	}
+
+	for j := 1; j <= len(s2); j++ {
+		curr[0] = j
+		c := s2[j-1]
+		for i := 1; i < n; i++ {
+			cost := 0
+			if s1[i-1] != c {
+				cost = 1
+			}
+			curr[i] = min3(
+				prev[i]+1,      // deletion
+				curr[i-1]+1,    // insertion
+				prev[i-1]+cost, // substitution
+			)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[n-1]
+}
+
+// OSA returns OSADistance(s1, s2) keeping only the three rows the
+// transposition step looks back on
+func (b *DistanceBuffer) OSA(s1, s2 string) int {
+	if s1 == s2 {
+		return 0
+	}
+	if len(s1) == 0 {
+		return len(s2)
+	}
+	if len(s2) == 0 {
+		return len(s1)
+	}
+
+	// The distance is symmetric, so keep the rows as short as possible
+	if len(s1) > len(s2) {
+		s1, s2 = s2, s1
+	}
+
+	n := len(s1) + 1
+	rows := b.rowsFor(3, n)
+	prev2, prev, curr := rows[:n], rows[n:2*n], rows[2*n:]
+
+	for i := range prev {
+		prev[i] = i
+	}
+
+	for j := 1; j <= len(s2); j++ {
+		curr[0] = j
+		for i := 1; i < n; i++ {
+			cost := 0
+			if s1[i-1] != s2[j-1] {
+				cost = 1
+			}
+
+			curr[i] = min3(
+				prev[i]+1,      // deletion
+				curr[i-1]+1,    // insertion
+				prev[i-1]+cost, // substitution
+			)
+
+			// Transposition
+			if i > 1 && j > 1 &&
+				s1[i-1] == s2[j-2] &&
+				s1[i-2] == s2[j-1] {
+				curr[i] = min(curr[i], prev2[i-2]+cost)
+			}
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+
+	return prev[n-1]
+}
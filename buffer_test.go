@@ -0,0 +1,105 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestDistanceBuffer(t *testing.T) {
+	var buf DistanceBuffer
+	rng := rand.New(rand.NewSource(1))
+
+	for iter := 0; iter < 500; iter++ {
+		s1 := randomString(rng, rng.Intn(20), "abc")
+		s2 := randomString(rng, rng.Intn(20), "abc")
+
+		if got, want := buf.Levenshtein(s1, s2), CompileQuery(s1).Distance(s2); got != want {
+			t.Fatalf("Levenshtein(%q, %q) = %d, want %d", s1, s2, got, want)
+		}
+		if got, want := buf.OSA(s1, s2), osaMatrixDistance(s1, s2); got != want {
+			t.Fatalf("OSA(%q, %q) = %d, want %d", s1, s2, got, want)
+		}
+	}
+
+	if got := buf.OSA("ca", "abc"); got != 3 {
+		t.Errorf(`OSA("ca", "abc") = %d, want 3`, got)
+	}
+	if got := buf.OSA("abcdef", "badcfe"); got != 3 {
+		t.Errorf(`OSA("abcdef", "badcfe") = %d, want 3`, got)
+	}
+}
+
+// osaMatrixDistance is the textbook full matrix OSA distance
+func osaMatrixDistance(s1, s2 string) int {
+	d := make([][]int, len(s1)+1)
+	for i := range d {
+		d[i] = make([]int, len(s2)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s1); i++ {
+		for j := 1; j <= len(s2); j++ {
+			cost := 0
+			if s1[i-1] != s2[j-1] {
+				cost = 1
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s1[i-1] == s2[j-2] && s1[i-2] == s2[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+cost)
+			}
+		}
+	}
+	return d[len(s1)][len(s2)]
+}
+
+func TestDistanceAllocations(t *testing.T) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+
+	var buf DistanceBuffer
+	buf.OSA(s1, s2)
+
+	funcs := map[string]DistanceFunc{
+		"LevenshteinDistance":        LevenshteinDistance,
+		"DamerauLevenshteinDistance": DamerauLevenshteinDistance,
+		"OSADistance":                OSADistance,
+		"DistanceBuffer.Levenshtein": buf.Levenshtein,
+		"DistanceBuffer.OSA":         buf.OSA,
+	}
+
+	for name, fn := range funcs {
+		allocs := testing.AllocsPerRun(100, func() {
+			fn(s1, s2)
+		})
+		if allocs != 0 {
+			t.Errorf("%s allocated %.0f times per call, want 0", name, allocs)
+		}
+	}
+}
+
+func BenchmarkDistanceBufferLevenshtein(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+	var buf DistanceBuffer
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Levenshtein(s1, s2)
+	}
+}
+
+func BenchmarkDistanceBufferOSA(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+	var buf DistanceBuffer
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.OSA(s1, s2)
+	}
+}
@@ -0,0 +1,399 @@
+package fuzzy
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"unsafe"
+)
+
+// ExternalBuildOptions configures BuildSuffixArrayFile
+type ExternalBuildOptions struct {
+	// MemoryBudget bounds the heap memory used for sorting, in bytes.
+	// Zero means 256MiB. On platforms without mmap the text and the
+	// working arrays are held in memory as well, outside the budget.
+	MemoryBudget int64
+
+	// WorkDir holds the temporary files, which take up to 16 bytes per
+	// text byte. Empty means os.TempDir().
+	WorkDir string
+
+	// Compact writes 32-bit suffix array entries, as SaveCompact does
+	Compact bool
+}
+
+const (
+	defaultMemoryBudget = 256 << 20
+
+	// sortBytesPerSuffix estimates the heap used by SA-IS and the run
+	// buffer per suffix sorted in memory
+	sortBytesPerSuffix = 40
+
+	// chunkContext is how many bytes of each suffix are compared while
+	// sorting chunks and merging runs; suffixes sharing a longer prefix
+	// are then ordered by prefix doubling
+	chunkContext = 256
+
+	minChunkSize = 1 << 10
+
+	intSize = strconv.IntSize / 8
+)
+
+// BuildSuffixArrayFile builds the suffix array of the text read from r and
+// writes it to path in the format of SuffixArray.Save, ready for
+// OpenSuffixArray. Unlike NewSuffixArray it never holds the text or the
+// suffix array on the heap where files can be memory-mapped: the text is
+// streamed into the output file and mapped, suffixes are sorted in chunks
+// that fit the memory budget and written to temporary files, and the
+// sorted runs are merged into a mapped working array. path is replaced
+// only once the build succeeds.
+func BuildSuffixArrayFile(r io.Reader, path string, opts ExternalBuildOptions) (err error) {
+	budget := opts.MemoryBudget
+	if budget <= 0 {
+		budget = defaultMemoryBudget
+	}
+
+	out, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = commitTemp(out, path, err)
+	}()
+
+	// Stream the text in after room for the header, which needs its length
+	const headerSize = 24
+	if _, err := out.Seek(headerSize, io.SeekStart); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	n64, err := io.Copy(w, r)
+	if err != nil {
+		return err
+	}
+	if int64(int(n64)) != n64 || n64 > math.MaxInt/intSize {
+		return errFileTooLarge
+	}
+	n := int(n64)
+	writePadding(w, n)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	width := 8
+	if opts.Compact && uint64(n) <= math.MaxUint32 {
+		width = 4
+	}
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.Reset(out)
+	writeSuffixArrayHeader(w, n, width)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	m, err := mapFile(out)
+	if err != nil {
+		return err
+	}
+	defer m.close()
+	text := m.data[headerSize : headerSize+n]
+
+	workDir, err := os.MkdirTemp(opts.WorkDir, "fuzzy-suffixarray-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	chunkSize := int(budget / sortBytesPerSuffix)
+	if chunkSize < minChunkSize {
+		chunkSize = minChunkSize
+	}
+
+	var runs []string
+	for start := 0; start < n; start += chunkSize {
+		end := min(start+chunkSize, n)
+		run := filepath.Join(workDir, fmt.Sprintf("run-%d", len(runs)))
+		if err := writeRun(run, sortChunk(text, start, end)); err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+
+	saMap, err := mapWorkFile(filepath.Join(workDir, "sa"), n*intSize)
+	if err != nil {
+		return err
+	}
+	defer saMap.close()
+	sa := saMap.ints()
+
+	ties, err := mergeRuns(sa, text, runs, int(budget)/(len(runs)+1))
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		os.Remove(run)
+	}
+
+	if ties {
+		invMap, err := mapWorkFile(filepath.Join(workDir, "inv"), n*intSize)
+		if err != nil {
+			return err
+		}
+		defer invMap.close()
+		refineTies(text, sa, invMap.ints())
+	}
+
+	if _, err := out.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	w.Reset(out)
+	for _, p := range sa {
+		writeUint(w, uint64(p), width)
+	}
+	return w.Flush()
+}
+
+// ints views mapped working data as native ints
+func (m *mapping) ints() []int {
+	if len(m.data) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*int)(unsafe.Pointer(&m.data[0])), len(m.data)/intSize)
+}
+
+// suffixPrefix returns the first chunkContext bytes of the suffix at i,
+// the part of it compared before prefix doubling
+func suffixPrefix(text []byte, i int) []byte {
+	return text[i:min(i+chunkContext, len(text))]
+}
+
+// sortChunk returns the suffixes starting in [start, end) ordered by their
+// first chunkContext bytes. SA-IS sorts them over a window reaching that
+// far past the chunk; suffixes equal within the window are left in
+// whatever order it gives them.
+func sortChunk(text []byte, start, end int) []int {
+	windowEnd := min(end+chunkContext, len(text))
+	order := sais(text[start:windowEnd], 255)
+
+	positions := make([]int, 0, end-start)
+	for _, s := range order {
+		if s < end-start {
+			positions = append(positions, start+s)
+		}
+	}
+	return positions
+}
+
+func writeRun(path string, positions []int) error {
+	return writeFile(path, func(w *bufio.Writer) error {
+		for _, p := range positions {
+			writeUint(w, uint64(p), 8)
+		}
+		return nil
+	})
+}
+
+// runReader yields the positions of one sorted run
+type runReader struct {
+	file *os.File
+	r    *bufio.Reader
+	buf  [8]byte
+	cur  int
+}
+
+func (rr *runReader) next() (bool, error) {
+	buf := rr.buf[:]
+	if _, err := io.ReadFull(rr.r, buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	rr.cur = int(binary.LittleEndian.Uint64(buf))
+	return true, nil
+}
+
+// runHeap orders run readers by the prefix of the suffix at their current
+// position
+type runHeap struct {
+	text []byte
+	runs []*runReader
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+func (h *runHeap) Less(i, j int) bool {
+	return bytes.Compare(suffixPrefix(h.text, h.runs[i].cur), suffixPrefix(h.text, h.runs[j].cur)) < 0
+}
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *runHeap) Push(x any)    { h.runs = append(h.runs, x.(*runReader)) }
+func (h *runHeap) Pop() any {
+	last := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return last
+}
+
+// mergeRuns merges the suffixes of all runs into sa, ordered by their first
+// chunkContext bytes, and reports whether any of them tie on those bytes
+func mergeRuns(sa []int, text []byte, paths []string, bufferSize int) (ties bool, err error) {
+	bufferSize = maxInt(bufferSize, 4096)
+	h := &runHeap{text: text}
+	defer func() {
+		for _, rr := range h.runs {
+			rr.file.Close()
+		}
+	}()
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return false, err
+		}
+		rr := &runReader{file: f, r: bufio.NewReaderSize(f, bufferSize)}
+		ok, err := rr.next()
+		if err != nil {
+			f.Close()
+			return false, err
+		}
+		if !ok {
+			f.Close()
+			continue
+		}
+		h.runs = append(h.runs, rr)
+	}
+	heap.Init(h)
+
+	for i := 0; h.Len() > 0; i++ {
+		rr := h.runs[0]
+		sa[i] = rr.cur
+		if i > 0 && !ties {
+			ties = bytes.Equal(suffixPrefix(text, sa[i-1]), suffixPrefix(text, sa[i]))
+		}
+
+		ok, err := rr.next()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			rr.file.Close()
+			heap.Pop(h)
+		}
+	}
+
+	return ties, nil
+}
+
+// refineTies finishes sorting sa, whose suffixes are ordered by their first
+// chunkContext bytes, by prefix doubling as in Larsson and Sadakane's
+// qsufsort. Each round sorts every group of suffixes sharing their first h
+// bytes by the group of the suffix h bytes later, ordering them by 2h
+// bytes. inv holds the group of each suffix, numbered by its last index,
+// and sorted groups are marked in sa by their negated length. It returns
+// the number of rounds, one more than the doublings of chunkContext needed
+// to tell every suffix apart.
+func refineTies(text []byte, sa, inv []int) (rounds int) {
+	n := len(sa)
+	for end := n - 1; end >= 0; {
+		start := end
+		for start > 0 && bytes.Equal(suffixPrefix(text, sa[start-1]), suffixPrefix(text, sa[end])) {
+			start--
+		}
+		for k := start; k <= end; k++ {
+			inv[sa[k]] = end
+		}
+		if start == end {
+			sa[end] = -1
+		}
+		end = start - 1
+	}
+
+	g := &tieGroup{inv: inv}
+	for g.h = chunkContext; sa[0] > -n; g.h *= 2 {
+		rounds++
+		pi := 0 // start of the current group
+		sl := 0 // negated length of the sorted groups just before pi
+		for pi < n {
+			if s := sa[pi]; s < 0 {
+				pi -= s
+				sl += s
+				continue
+			}
+			if sl != 0 {
+				sa[pi+sl] = sl // combine the sorted groups before pi
+				sl = 0
+			}
+			pk := inv[sa[pi]] + 1
+			g.sa, g.lo = sa[pi:pk], pi
+			sort.Sort(g)
+			g.split()
+			pi = pk
+		}
+		if sl != 0 {
+			sa[pi+sl] = sl
+		}
+	}
+
+	// Every group is a single suffix, so inv is the inverse suffix array
+	for i, r := range inv {
+		sa[r] = i
+	}
+	return rounds
+}
+
+// tieGroup is a group of suffixes sharing their first h bytes, starting at
+// index lo of the suffix array
+type tieGroup struct {
+	sa  []int
+	inv []int
+	lo  int
+	h   int
+}
+
+// key is the group of the suffix h bytes after sa[i], or -1 past the end of
+// the text. Suffixes in this group count as one, whatever numbers split has
+// given them, so keys stay comparable while the group is renumbered.
+func (g *tieGroup) key(i int) int {
+	p := g.sa[i] + g.h
+	if p >= len(g.inv) {
+		return -1
+	}
+	hi := g.lo + len(g.sa) - 1
+	if k := g.inv[p]; k < g.lo || k > hi {
+		return k
+	}
+	return hi
+}
+
+func (g *tieGroup) Len() int           { return len(g.sa) }
+func (g *tieGroup) Less(i, j int) bool { return g.key(i) < g.key(j) }
+func (g *tieGroup) Swap(i, j int)      { g.sa[i], g.sa[j] = g.sa[j], g.sa[i] }
+
+// split numbers each run of equal keys in the sorted group by its last
+// index, marking runs of a single suffix as sorted
+func (g *tieGroup) split() {
+	end := len(g.sa) - 1
+	for i := end; i >= 0; i-- {
+		if i > 0 && g.key(i-1) == g.key(i) {
+			continue
+		}
+		for k := i; k <= end; k++ {
+			g.inv[g.sa[k]] = g.lo + end
+		}
+		if i == end {
+			g.sa[i] = -1
+		}
+		end = i - 1
+	}
+}
@@ -0,0 +1,176 @@
+package fuzzy
+
+import (
+	"errors"
+	"math/bits"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildSuffixArrayFile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	block := randomString(rng, 1500, "ab")
+
+	texts := map[string]string{
+		"empty":      "",
+		"small":      "mississippi",
+		"random":     randomString(rng, 20000, "abcd"),
+		"repetitive": strings.Repeat(block, 4) + "a",
+		"words":      strings.Repeat("algorithm database network ", 500),
+	}
+
+	for name, text := range texts {
+		for _, compact := range []bool{false, true} {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "index.sa")
+			opts := ExternalBuildOptions{
+				MemoryBudget: 100 << 10, // 2560 suffixes per run
+				WorkDir:      dir,
+				Compact:      compact,
+			}
+
+			if err := BuildSuffixArrayFile(strings.NewReader(text), path, opts); err != nil {
+				t.Fatalf("%s: BuildSuffixArrayFile: %v", name, err)
+			}
+
+			sa, err := OpenSuffixArray(path)
+			if err != nil {
+				t.Fatalf("%s: OpenSuffixArray: %v", name, err)
+			}
+			want := NewSuffixArray(text)
+			if sa.text != text || !reflect.DeepEqual(sa.suffixes, want.suffixes) {
+				t.Errorf("%s (compact %v): external suffix array differs from NewSuffixArray", name, compact)
+			}
+			sa.Close()
+
+			// Only the output file is left behind
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Errorf("%s: work directory holds %d entries, want only the index", name, len(entries))
+			}
+		}
+	}
+}
+
+// failingReader returns its text and then an error
+type failingReader struct{ text string }
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.text == "" {
+		return 0, errors.New("read failed")
+	}
+	n := copy(p, r.text)
+	r.text = r.text[n:]
+	return n, nil
+}
+
+func TestBuildSuffixArrayFileFailure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.sa")
+	if err := NewSuffixArray("banana").Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	err := BuildSuffixArrayFile(&failingReader{text: "mississippi"}, path, ExternalBuildOptions{WorkDir: dir})
+	if err == nil {
+		t.Fatal("BuildSuffixArrayFile succeeded, want the read error")
+	}
+
+	// The old index is untouched and nothing else is left behind
+	sa, err := OpenSuffixArray(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sa.text != "banana" {
+		t.Errorf("index text after a failed build = %q, want banana", sa.text)
+	}
+	sa.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries after a failed build, want 1", len(entries))
+	}
+}
+
+func TestBuildSuffixArrayFileRepetitive(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	texts := map[string]string{
+		"run":      strings.Repeat("a", 256<<10),
+		"period-3": strings.Repeat("abc", 100<<10),
+		"blocks":   strings.Repeat(randomString(rng, 5000, "ab"), 40),
+	}
+	for i := 0; i < 20; i++ {
+		period := randomString(rng, 1+rng.Intn(20), "ab")
+		texts[period] = strings.Repeat(period, 1+rng.Intn(3000)) + randomString(rng, rng.Intn(3), "ab")
+	}
+
+	for name, text := range texts {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "index.sa")
+		opts := ExternalBuildOptions{MemoryBudget: 1 << 20, WorkDir: dir}
+
+		if err := BuildSuffixArrayFile(strings.NewReader(text), path, opts); err != nil {
+			t.Fatalf("%s: BuildSuffixArrayFile: %v", name, err)
+		}
+
+		sa, err := OpenSuffixArray(path)
+		if err != nil {
+			t.Fatalf("%s: OpenSuffixArray: %v", name, err)
+		}
+		if want := NewSuffixArray(text); !reflect.DeepEqual(sa.suffixes, want.suffixes) {
+			t.Errorf("%s: external suffix array differs from NewSuffixArray", name)
+		}
+		sa.Close()
+	}
+}
+
+func TestRefineTiesRounds(t *testing.T) {
+	for _, n := range []int{chunkContext + 1, 4 * chunkContext, 1000 * chunkContext} {
+		text := []byte(strings.Repeat("a", n))
+		sa := sortChunk(text, 0, n)
+		inv := make([]int, n)
+
+		// Each round doubles the sorted prefix length, and comparisons look
+		// up a group number instead of reading the suffixes
+		rounds := refineTies(text, sa, inv)
+		if limit := bits.Len(uint(n/chunkContext)) + 1; rounds > limit {
+			t.Errorf("n = %d: %d prefix doubling rounds, want at most %d", n, rounds, limit)
+		}
+		if want := NewSuffixArray(string(text)); !reflect.DeepEqual(sa, want.suffixes) {
+			t.Errorf("n = %d: refined suffix array differs from NewSuffixArray", n)
+		}
+	}
+}
+
+func BenchmarkBuildSuffixArrayFile(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for sb.Len() < 1<<20 {
+		sb.WriteString(randomString(rng, 3+rng.Intn(8), "abcdefghijklmnopqrstuvwxyz"))
+		sb.WriteByte(' ')
+	}
+	texts := []struct {
+		name string
+		text string
+	}{
+		{"words", sb.String()},
+		{"repetitive", strings.Repeat("a", 1<<20)},
+	}
+
+	for _, tt := range texts {
+		b.Run(tt.name, func(b *testing.B) {
+			dir := b.TempDir()
+			opts := ExternalBuildOptions{MemoryBudget: 8 << 20, WorkDir: dir}
+
+			b.SetBytes(int64(len(tt.text)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := BuildSuffixArrayFile(strings.NewReader(tt.text), filepath.Join(dir, "index.sa"), opts); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package fuzzy
+
+import "sort"
+
+// FuzzyLocate finds the text positions where a substring within maxEdits
+// insertions, deletions or substitutions of pattern starts. Each start is
+// reported once with its smallest distance and the longest substring
+// reaching it, ordered by Start. The BWT is explored by backtracking, so
+// only text sharing long enough pieces with pattern is ever visited.
+func (fm *FMIndex) FuzzyLocate(pattern string, maxEdits int) []Match {
+	return fm.locateHits(fm.fuzzyRows(pattern, maxEdits, true))
+}
+
+// FuzzyCount returns the number of positions FuzzyLocate would report,
+// without walking back to their text offsets
+func (fm *FMIndex) FuzzyCount(pattern string, maxEdits int) int {
+	return len(fm.fuzzyRows(pattern, maxEdits, true))
+}
+
+// MismatchLocate finds the substrings of the same length as pattern that
+// differ from it in at most maxMismatches bytes, ordered by Start
+func (fm *FMIndex) MismatchLocate(pattern string, maxMismatches int) []Match {
+	return fm.locateHits(fm.fuzzyRows(pattern, maxMismatches, false))
+}
+
+// MismatchCount returns the number of substrings MismatchLocate would report
+func (fm *FMIndex) MismatchCount(pattern string, maxMismatches int) int {
+	return len(fm.fuzzyRows(pattern, maxMismatches, false))
+}
+
+// fuzzyHit is the best alignment found ending the search at a BWT row
+type fuzzyHit struct {
+	distance int
+	length   int
+}
+
+// fuzzySearch holds the state of one backtracking search. The pattern is
+// consumed from its last byte to its first, extending the matched text to
+// the left one BWT step at a time, as in BWA.
+type fuzzySearch struct {
+	fm       *FMIndex
+	pattern  string
+	maxEdits int
+	edits    bool // Allow insertions and deletions, not only substitutions
+
+	// lowerBound[l] is a lower bound on the edits needed to match the
+	// first l bytes of the pattern anywhere in the text
+	lowerBound []int
+
+	hits map[int]fuzzyHit
+}
+
+// fuzzyRows returns the best hit for every BWT row whose suffix starts with
+// an approximate match of pattern
+func (fm *FMIndex) fuzzyRows(pattern string, maxEdits int, edits bool) map[int]fuzzyHit {
+	hits := make(map[int]fuzzyHit)
+	if len(pattern) == 0 || maxEdits < 0 {
+		return hits
+	}
+
+	s := &fuzzySearch{
+		fm:         fm,
+		pattern:    pattern,
+		maxEdits:   maxEdits,
+		edits:      edits,
+		lowerBound: fm.lowerBounds(pattern),
+		hits:       hits,
+	}
+	// Row 0, the empty suffix at the end of the text, starts the search so
+	// matches may end with the last text byte
+	s.extend(len(pattern)-1, 0, fm.counts[256]-1, maxEdits, 0)
+
+	return hits
+}
+
+// lowerBounds splits each prefix of pattern, from its end, into the fewest
+// pieces that do not occur in the text. Every such piece needs at least one
+// edit, so the count bounds the edit distance of the prefix from below.
+func (fm *FMIndex) lowerBounds(pattern string) []int {
+	bounds := make([]int, len(pattern)+1)
+	all, last := 0, fm.counts[256]-1
+
+	for l := 1; l <= len(pattern); l++ {
+		sp, ep := all, last
+		for j := l - 1; j >= 0; j-- {
+			c := pattern[j]
+			sp = fm.counts[c] + fm.occ(c, sp)
+			ep = fm.counts[c] + fm.occ(c, ep+1) - 1
+			if sp > ep {
+				bounds[l]++
+				sp, ep = all, last
+			}
+		}
+	}
+
+	return bounds
+}
+
+// extend matches pattern[:i+1] to the left of the rows [sp, ep], whose
+// suffixes start with length bytes already aligned, with z edits left
+func (s *fuzzySearch) extend(i, sp, ep, z, length int) {
+	fm := s.fm
+
+	if i < 0 {
+		s.record(sp, ep, s.maxEdits-z, length)
+		if !s.edits || z == 0 {
+			return
+		}
+	} else if z < s.lowerBound[i+1] {
+		return
+	}
+
+	// Deleting pattern[i] leaves the rows unchanged
+	if s.edits && i >= 0 && z > 0 {
+		s.extend(i-1, sp, ep, z-1, length)
+	}
+
+	for c := 0; c < 256; c++ {
+		if fm.counts[c] == fm.counts[c+1] {
+			continue // byte absent from the text
+		}
+		b := byte(c)
+		nsp := fm.counts[c] + fm.occ(b, sp)
+		nep := fm.counts[c] + fm.occ(b, ep+1) - 1
+		if nsp > nep {
+			continue
+		}
+
+		if i >= 0 {
+			if b == s.pattern[i] {
+				s.extend(i-1, nsp, nep, z, length+1)
+			} else if z > 0 {
+				s.extend(i-1, nsp, nep, z-1, length+1)
+			}
+		}
+
+		// Inserting a text byte before pattern[i+1]. An insertion after the
+		// last pattern byte only lengthens a match that already exists, so
+		// the search does not start with one.
+		if s.edits && z > 0 && i < len(s.pattern)-1 {
+			s.extend(i, nsp, nep, z-1, length+1)
+		}
+	}
+}
+
+// record keeps the best hit for each row in [sp, ep], except for the empty
+// suffix at the end of the text
+func (s *fuzzySearch) record(sp, ep, distance, length int) {
+	for row := maxInt(sp, 1); row <= ep; row++ {
+		best, ok := s.hits[row]
+		if !ok || distance < best.distance || (distance == best.distance && length > best.length) {
+			s.hits[row] = fuzzyHit{distance: distance, length: length}
+		}
+	}
+}
+
+// locateHits resolves the text offsets of hits, ordered by position
+func (fm *FMIndex) locateHits(hits map[int]fuzzyHit) []Match {
+	matches := make([]Match, 0, len(hits))
+	for row, hit := range hits {
+		start := fm.position(row)
+		matches = append(matches, Match{
+			Start:    start,
+			End:      start + hit.length,
+			Distance: hit.distance,
+		})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Start < matches[j].Start
+	})
+	return matches
+}
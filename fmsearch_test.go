@@ -0,0 +1,138 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// bruteFuzzyLocate returns, for every start, the smallest edit distance of
+// any substring starting there, and the longest substring reaching it
+func bruteFuzzyLocate(text, pattern string, k int) []Match {
+	var matches []Match
+	for start := 0; start < len(text); start++ {
+		best := Match{Distance: k + 1}
+		for end := start; end <= len(text); end++ {
+			if d := LevenshteinDistance(pattern, text[start:end]); d <= best.Distance {
+				best = Match{Start: start, End: end, Distance: d}
+			}
+		}
+		if best.Distance <= k {
+			matches = append(matches, best)
+		}
+	}
+	return matches
+}
+
+func bruteMismatchLocate(text, pattern string, k int) []Match {
+	var matches []Match
+	for start := 0; start+len(pattern) <= len(text); start++ {
+		d := 0
+		for i := 0; i < len(pattern); i++ {
+			if text[start+i] != pattern[i] {
+				d++
+			}
+		}
+		if d <= k {
+			matches = append(matches, Match{Start: start, End: start + len(pattern), Distance: d})
+		}
+	}
+	return matches
+}
+
+func TestFMIndexFuzzyLocate(t *testing.T) {
+	text := "The quick brown fox jumps over the lazy dog"
+	fm := NewFMIndex(text, 4)
+
+	matches := fm.FuzzyLocate("quik", 1)
+	found := false
+	for _, m := range matches {
+		if m.Start == 4 && m.End == 9 && m.Distance == 1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("FuzzyLocate(\"quik\", 1) = %v, want a match of \"quick\" at 4", matches)
+	}
+
+	if got := fm.FuzzyLocate("fox", 0); !reflect.DeepEqual(got, []Match{{Start: 16, End: 19}}) {
+		t.Errorf("FuzzyLocate(\"fox\", 0) = %v, want exact match at 16", got)
+	}
+	if got := fm.FuzzyLocate("", 1); len(got) != 0 {
+		t.Errorf("FuzzyLocate of an empty pattern = %v, want none", got)
+	}
+}
+
+func TestFMIndexFuzzyLocateMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 100; iter++ {
+		text := randomString(rng, 1+rng.Intn(60), "abcd")
+		pattern := randomString(rng, 1+rng.Intn(6), "abcd")
+		k := rng.Intn(3)
+		fm := NewFMIndex(text, 1+rng.Intn(8))
+
+		want := bruteFuzzyLocate(text, pattern, k)
+		got := fm.FuzzyLocate(pattern, k)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("FuzzyLocate(%q, %d) in %q = %v, want %v", pattern, k, text, got, want)
+		}
+		if count := fm.FuzzyCount(pattern, k); count != len(want) {
+			t.Fatalf("FuzzyCount(%q, %d) = %d, want %d", pattern, k, count, len(want))
+		}
+	}
+}
+
+func TestFMIndexMismatchLocate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		text := randomString(rng, 1+rng.Intn(80), "acgt")
+		pattern := randomString(rng, 1+rng.Intn(8), "acgt")
+		k := rng.Intn(3)
+		fm := NewFMIndex(text, 1+rng.Intn(8))
+
+		want := bruteMismatchLocate(text, pattern, k)
+		got := fm.MismatchLocate(pattern, k)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("MismatchLocate(%q, %d) in %q = %v, want %v", pattern, k, text, got, want)
+		}
+		if count := fm.MismatchCount(pattern, k); count != len(want) {
+			t.Fatalf("MismatchCount(%q, %d) = %d, want %d", pattern, k, count, len(want))
+		}
+	}
+}
+
+func fuzzyBenchmarkText() string {
+	rng := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for sb.Len() < 4<<10 {
+		sb.WriteString(randomString(rng, 3+rng.Intn(8), "abcdefghijklmnopqrstuvwxyz"))
+		sb.WriteByte(' ')
+	}
+	sb.WriteString("algorithm")
+	return sb.String()
+}
+
+func BenchmarkFMIndexFuzzyLocate(b *testing.B) {
+	fm := NewFMIndex(fuzzyBenchmarkText(), 16)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fm.FuzzyLocate("algoritm", 1)
+	}
+}
+
+func BenchmarkSuffixArrayFuzzySearch(b *testing.B) {
+	sa := NewSuffixArray(fuzzyBenchmarkText())
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sa.FuzzySearch("algoritm", 1)
+	}
+}
@@ -0,0 +1,237 @@
+package fuzzy
+
+import "sort"
+
+// GeneralizedSuffixArray indexes a collection of documents at once. Each
+// document is terminated by its own sentinel while sorting, so no match ever
+// spans two documents and results are reported per document.
+type GeneralizedSuffixArray struct {
+	docs     []string
+	suffixes []int // Suffixes in sorted order, as offsets into their document
+	docIDs   []int // Document of each entry of suffixes
+}
+
+// DocumentMatch is a match inside one document of a GeneralizedSuffixArray,
+// covering Doc's bytes [Start, End)
+type DocumentMatch struct {
+	Doc      int
+	Start    int
+	End      int
+	Distance int
+}
+
+// NewGeneralizedSuffixArray builds a suffix array over docs, identified by
+// their index in the slice
+func NewGeneralizedSuffixArray(docs []string) *GeneralizedSuffixArray {
+	gsa := &GeneralizedSuffixArray{docs: docs}
+	gsa.build()
+	return gsa
+}
+
+// build sorts the concatenation of all documents over an integer alphabet
+// in which document i ends with the sentinel i and byte b becomes
+// len(docs)+b, so sentinels are unique and sort before every byte
+func (gsa *GeneralizedSuffixArray) build() {
+	d := len(gsa.docs)
+
+	total := 0
+	for _, doc := range gsa.docs {
+		total += len(doc) + 1
+	}
+
+	text := make([]int, 0, total)
+	owner := make([]int, 0, total)
+	offset := make([]int, 0, total)
+	for id, doc := range gsa.docs {
+		for i := 0; i < len(doc); i++ {
+			text = append(text, d+int(doc[i]))
+			owner = append(owner, id)
+			offset = append(offset, i)
+		}
+		text = append(text, id)
+		owner = append(owner, id)
+		offset = append(offset, -1)
+	}
+
+	sorted := SortSuffixes(text, d+256)
+
+	gsa.suffixes = make([]int, 0, total-d)
+	gsa.docIDs = make([]int, 0, total-d)
+	for _, pos := range sorted {
+		if offset[pos] < 0 {
+			continue // suffix starting at a sentinel
+		}
+		gsa.suffixes = append(gsa.suffixes, offset[pos])
+		gsa.docIDs = append(gsa.docIDs, owner[pos])
+	}
+}
+
+// Documents returns the number of indexed documents
+func (gsa *GeneralizedSuffixArray) Documents() int {
+	return len(gsa.docs)
+}
+
+// Document returns the document with the given ID
+func (gsa *GeneralizedSuffixArray) Document(id int) string {
+	return gsa.docs[id]
+}
+
+// suffix returns the i-th suffix in sorted order, up to its document's end
+func (gsa *GeneralizedSuffixArray) suffix(i int) string {
+	return gsa.docs[gsa.docIDs[i]][gsa.suffixes[i]:]
+}
+
+// prefixRange returns the range [lo, hi) of entries whose suffixes start
+// with prefix
+func (gsa *GeneralizedSuffixArray) prefixRange(prefix string) (int, int) {
+	n := len(gsa.suffixes)
+	m := len(prefix)
+	truncated := func(i int) string {
+		s := gsa.suffix(i)
+		if len(s) > m {
+			return s[:m]
+		}
+		return s
+	}
+
+	lo := sort.Search(n, func(i int) bool {
+		return truncated(i) >= prefix
+	})
+	hi := lo + sort.Search(n-lo, func(i int) bool {
+		return truncated(lo+i) > prefix
+	})
+	return lo, hi
+}
+
+// Search returns every occurrence of pattern, ordered by document and offset
+func (gsa *GeneralizedSuffixArray) Search(pattern string) []DocumentMatch {
+	if len(pattern) == 0 {
+		return nil
+	}
+
+	lo, hi := gsa.prefixRange(pattern)
+	if lo == hi {
+		return nil
+	}
+
+	matches := make([]DocumentMatch, 0, hi-lo)
+	for i := lo; i < hi; i++ {
+		matches = append(matches, DocumentMatch{
+			Doc:   gsa.docIDs[i],
+			Start: gsa.suffixes[i],
+			End:   gsa.suffixes[i] + len(pattern),
+		})
+	}
+	sortDocumentMatches(matches)
+
+	return matches
+}
+
+// DocumentFrequency returns the number of documents containing pattern
+func (gsa *GeneralizedSuffixArray) DocumentFrequency(pattern string) int {
+	if len(pattern) == 0 {
+		return 0
+	}
+
+	lo, hi := gsa.prefixRange(pattern)
+	seen := make(map[int]struct{})
+	for i := lo; i < hi; i++ {
+		seen[gsa.docIDs[i]] = struct{}{}
+	}
+	return len(seen)
+}
+
+// FuzzySearch finds the positions where a substring within maxErrors edits
+// of pattern starts. Each position is reported once with its smallest
+// distance and the longest substring reaching it, ordered by document and
+// offset. The suffix array is walked depth first, sharing one column of
+// the edit distance table between all suffixes with a common prefix and
+// abandoning prefixes that can no longer match.
+func (gsa *GeneralizedSuffixArray) FuzzySearch(pattern string, maxErrors int) []DocumentMatch {
+	if len(pattern) == 0 || maxErrors < 0 {
+		return nil
+	}
+
+	best := make(map[int]DocumentMatch)
+	record := func(lo, hi, length, distance int) {
+		for i := lo; i < hi; i++ {
+			prev, ok := best[i]
+			if ok && (prev.Distance < distance || (prev.Distance == distance && prev.End-prev.Start >= length)) {
+				continue
+			}
+			best[i] = DocumentMatch{
+				Doc:      gsa.docIDs[i],
+				Start:    gsa.suffixes[i],
+				End:      gsa.suffixes[i] + length,
+				Distance: distance,
+			}
+		}
+	}
+
+	m := len(pattern)
+	col := make([]int, m+1)
+	for j := range col {
+		col[j] = j
+	}
+	if m <= maxErrors {
+		record(0, len(gsa.suffixes), 0, m)
+	}
+	gsa.fuzzyWalk(pattern, maxErrors, 0, len(gsa.suffixes), 0, col, record)
+
+	matches := make([]DocumentMatch, 0, len(best))
+	for _, match := range best {
+		matches = append(matches, match)
+	}
+	sortDocumentMatches(matches)
+
+	return matches
+}
+
+// fuzzyWalk extends the suffixes in [lo, hi), which share their first depth
+// bytes, by each following byte in turn. col holds the edit distances
+// between the prefixes of pattern and those shared bytes.
+func (gsa *GeneralizedSuffixArray) fuzzyWalk(pattern string, maxErrors, lo, hi, depth int, col []int, record func(lo, hi, length, distance int)) {
+	m := len(pattern)
+	next := make([]int, m+1)
+
+	// Suffixes ending at depth sort first and cannot be extended
+	for lo < hi && len(gsa.suffix(lo)) <= depth {
+		lo++
+	}
+
+	for lo < hi {
+		c := gsa.suffix(lo)[depth]
+		end := lo + sort.Search(hi-lo, func(i int) bool {
+			return gsa.suffix(lo + i)[depth] > c
+		})
+
+		next[0] = depth + 1
+		lowest := next[0]
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if pattern[j-1] == c {
+				cost = 0
+			}
+			next[j] = min3(col[j]+1, next[j-1]+1, col[j-1]+cost)
+			lowest = min(lowest, next[j])
+		}
+
+		if next[m] <= maxErrors {
+			record(lo, end, depth+1, next[m])
+		}
+		if lowest <= maxErrors {
+			gsa.fuzzyWalk(pattern, maxErrors, lo, end, depth+1, next, record)
+		}
+
+		lo = end
+	}
+}
+
+func sortDocumentMatches(matches []DocumentMatch) {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Doc != matches[j].Doc {
+			return matches[i].Doc < matches[j].Doc
+		}
+		return matches[i].Start < matches[j].Start
+	})
+}
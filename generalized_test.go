@@ -0,0 +1,118 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGeneralizedSuffixArraySearch(t *testing.T) {
+	docs := []string{"banana", "bandana", "", "cabana", "ana"}
+	gsa := NewGeneralizedSuffixArray(docs)
+
+	got := gsa.Search("ana")
+	want := []DocumentMatch{
+		{Doc: 0, Start: 1, End: 4},
+		{Doc: 0, Start: 3, End: 6},
+		{Doc: 1, Start: 4, End: 7},
+		{Doc: 3, Start: 3, End: 6},
+		{Doc: 4, Start: 0, End: 3},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Search(\"ana\") = %v, want %v", got, want)
+	}
+
+	// "anab" only exists across the boundary of "banana" and "bandana"
+	if got := gsa.Search("anab"); len(got) != 0 {
+		t.Errorf("Search(\"anab\") = %v, want no match spanning documents", got)
+	}
+
+	if got := gsa.DocumentFrequency("ana"); got != 4 {
+		t.Errorf("DocumentFrequency(\"ana\") = %d, want 4", got)
+	}
+	if got := gsa.DocumentFrequency("band"); got != 1 {
+		t.Errorf("DocumentFrequency(\"band\") = %d, want 1", got)
+	}
+	if got := gsa.DocumentFrequency("xyz"); got != 0 {
+		t.Errorf("DocumentFrequency(\"xyz\") = %d, want 0", got)
+	}
+}
+
+func TestGeneralizedSuffixArrayMatchesSingle(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 100; iter++ {
+		docs := make([]string, 1+rng.Intn(5))
+		for i := range docs {
+			docs[i] = randomString(rng, rng.Intn(30), "abc")
+		}
+		gsa := NewGeneralizedSuffixArray(docs)
+		pattern := randomString(rng, 1+rng.Intn(3), "abc")
+
+		var want []DocumentMatch
+		for id, doc := range docs {
+			for i := 0; i+len(pattern) <= len(doc); i++ {
+				if doc[i:i+len(pattern)] == pattern {
+					want = append(want, DocumentMatch{Doc: id, Start: i, End: i + len(pattern)})
+				}
+			}
+		}
+
+		if got := gsa.Search(pattern); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Search(%q) in %q = %v, want %v", pattern, docs, got, want)
+		}
+	}
+}
+
+func TestGeneralizedSuffixArrayFuzzySearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 100; iter++ {
+		docs := make([]string, 1+rng.Intn(4))
+		for i := range docs {
+			docs[i] = randomString(rng, rng.Intn(25), "abcd")
+		}
+		pattern := randomString(rng, 1+rng.Intn(5), "abcd")
+		k := rng.Intn(3)
+
+		var want []DocumentMatch
+		for id, doc := range docs {
+			for _, m := range bruteFuzzyLocate(doc, pattern, k) {
+				want = append(want, DocumentMatch{Doc: id, Start: m.Start, End: m.End, Distance: m.Distance})
+			}
+		}
+
+		got := NewGeneralizedSuffixArray(docs).FuzzySearch(pattern, k)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("FuzzySearch(%q, %d) in %q = %v, want %v", pattern, k, docs, got, want)
+		}
+	}
+}
+
+func BenchmarkGeneralizedSuffixArrayBuild(b *testing.B) {
+	docs := make([]string, 100)
+	for i := range docs {
+		docs[i] = strings.Repeat("The quick brown fox jumps over the lazy dog. ", 10)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewGeneralizedSuffixArray(docs)
+	}
+}
+
+func BenchmarkGeneralizedSuffixArrayFuzzySearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	docs := make([]string, 100)
+	for i := range docs {
+		docs[i] = randomString(rng, 200, "abcdefghijklmnopqrstuvwxyz ")
+	}
+	gsa := NewGeneralizedSuffixArray(docs)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		gsa.FuzzySearch("algoritm", 1)
+	}
+}
@@ -0,0 +1,72 @@
+package fuzzy
+
+// JaroSimilarity scores two strings by the characters they share within a
+// window of half the longer length, penalizing shared characters that
+// appear out of order. It is 1 for identical strings and 0 when nothing
+// matches.
+func JaroSimilarity(s1, s2 string) float64 {
+	if s1 == s2 {
+		return 1
+	}
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	window := maxInt(len(s1), len(s2))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+
+	matched1 := make([]bool, len(s1))
+	matched2 := make([]bool, len(s2))
+
+	matches := 0
+	for i := 0; i < len(s1); i++ {
+		lo := maxInt(0, i-window)
+		hi := min(len(s2), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matched2[j] && s1[i] == s2[j] {
+				matched1[i] = true
+				matched2[j] = true
+				matches++
+				break
+			}
+		}
+	}
+
+	if matches == 0 {
+		return 0
+	}
+
+	// Count matched characters that appear in a different order
+	transpositions := 0
+	j := 0
+	for i := 0; i < len(s1); i++ {
+		if !matched1[i] {
+			continue
+		}
+		for !matched2[j] {
+			j++
+		}
+		if s1[i] != s2[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	return (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions/2))/m) / 3
+}
+
+// JaroWinklerSimilarity boosts JaroSimilarity for strings sharing a prefix of
+// up to four characters, using Winkler's standard scaling factor of 0.1
+func JaroWinklerSimilarity(s1, s2 string) float64 {
+	sim := JaroSimilarity(s1, s2)
+
+	prefix := 0
+	for prefix < 4 && prefix < len(s1) && prefix < len(s2) && s1[prefix] == s2[prefix] {
+		prefix++
+	}
+
+	return sim + float64(prefix)*0.1*(1-sim)
+}
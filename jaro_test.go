@@ -0,0 +1,41 @@
+package fuzzy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJaroSimilarity(t *testing.T) {
+	tests := []struct {
+		s1, s2  string
+		jaro    float64
+		winkler float64
+	}{
+		{"", "", 1, 1},
+		{"abc", "", 0, 0},
+		{"abc", "xyz", 0, 0},
+		{"MARTHA", "MARHTA", 0.944444, 0.961111},
+		{"DWAYNE", "DUANE", 0.822222, 0.840000},
+		{"DIXON", "DICKSONX", 0.766667, 0.813333},
+		{"CRATE", "TRACE", 0.733333, 0.733333},
+	}
+
+	for _, tt := range tests {
+		if got := JaroSimilarity(tt.s1, tt.s2); math.Abs(got-tt.jaro) > 1e-6 {
+			t.Errorf("JaroSimilarity(%q, %q) = %f, want %f", tt.s1, tt.s2, got, tt.jaro)
+		}
+		if got := JaroWinklerSimilarity(tt.s1, tt.s2); math.Abs(got-tt.winkler) > 1e-6 {
+			t.Errorf("JaroWinklerSimilarity(%q, %q) = %f, want %f", tt.s1, tt.s2, got, tt.winkler)
+		}
+	}
+}
+
+func BenchmarkJaroWinkler(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		JaroWinklerSimilarity(s1, s2)
+	}
+}
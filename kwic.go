@@ -0,0 +1,95 @@
+package fuzzy
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// KWIC is a keyword-in-context hit: one occurrence of a pattern with the
+// text around it, so Before+Match+After is a contiguous part of the text
+type KWIC struct {
+	Position int
+	Before   string
+	Match    string
+	After    string
+}
+
+// KWICOptions selects the hits returned by SuffixArray.KWIC and how much
+// context surrounds them
+type KWICOptions struct {
+	SearchOptions
+
+	// Context is the number of bytes on each side of a match, shortened so
+	// no UTF-8 sequence is split. With Lines it is instead the number of
+	// whole lines around the lines holding the match.
+	Context int
+	Lines   bool
+}
+
+// KWIC returns the occurrences of pattern with their context, ordered and
+// paged by the embedded SearchOptions
+func (sa *SuffixArray) KWIC(pattern string, opts KWICOptions) []KWIC {
+	positions := sa.SearchWithOptions(pattern, opts.SearchOptions)
+	if positions == nil {
+		return nil
+	}
+
+	context := maxInt(opts.Context, 0)
+	hits := make([]KWIC, len(positions))
+	for i, pos := range positions {
+		end := pos + len(pattern)
+
+		var from, to int
+		if opts.Lines {
+			from, to = sa.lineContext(pos, end, context)
+		} else {
+			from, to = sa.byteContext(pos, end, context)
+		}
+
+		text := sa.substring(from, to)
+		hits[i] = KWIC{
+			Position: pos,
+			Before:   text[:pos-from],
+			Match:    text[pos-from : end-from],
+			After:    text[end-from:],
+		}
+	}
+	return hits
+}
+
+// byteContext returns the range reaching up to context bytes around
+// [start, end), trimmed inwards to rune boundaries
+func (sa *SuffixArray) byteContext(start, end, context int) (int, int) {
+	from := maxInt(start-context, 0)
+	for from < start && !utf8.RuneStart(sa.text[from]) {
+		from++
+	}
+
+	to := min(end+context, len(sa.text))
+	for to > end && to < len(sa.text) && !utf8.RuneStart(sa.text[to]) {
+		to--
+	}
+	return from, to
+}
+
+// lineContext returns the range of the lines holding [start, end) and of
+// context lines before and after them, without the final newline
+func (sa *SuffixArray) lineContext(start, end, context int) (int, int) {
+	from := strings.LastIndexByte(sa.text[:start], '\n') + 1
+	for i := 0; i < context && from > 0; i++ {
+		from = strings.LastIndexByte(sa.text[:from-1], '\n') + 1
+	}
+
+	to := end
+	for i := 0; i <= context && to < len(sa.text); i++ {
+		if i > 0 {
+			to++ // step over the newline ending the previous line
+		}
+		next := strings.IndexByte(sa.text[to:], '\n')
+		if next < 0 {
+			return from, len(sa.text)
+		}
+		to += next
+	}
+	return from, to
+}
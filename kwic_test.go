@@ -0,0 +1,95 @@
+package fuzzy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestKWICBytes(t *testing.T) {
+	text := "the cat sat on the mat with the hat"
+	sa := NewSuffixArray(text)
+
+	got := sa.KWIC("the", KWICOptions{SearchOptions: SearchOptions{Sorted: true}, Context: 4})
+	want := []KWIC{
+		{Position: 0, Before: "", Match: "the", After: " cat"},
+		{Position: 15, Before: " on ", Match: "the", After: " mat"},
+		{Position: 28, Before: "ith ", Match: "the", After: " hat"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("KWIC = %+v, want %+v", got, want)
+	}
+
+	paged := sa.KWIC("the", KWICOptions{SearchOptions: SearchOptions{Sorted: true, Offset: 1, Limit: 1}, Context: 4})
+	if !reflect.DeepEqual(paged, want[1:2]) {
+		t.Errorf("paged KWIC = %+v, want %+v", paged, want[1:2])
+	}
+
+	if hits := sa.KWIC("dog", KWICOptions{Context: 4}); hits != nil {
+		t.Errorf("KWIC(dog) = %+v, want nil", hits)
+	}
+}
+
+func TestKWICRuneBoundaries(t *testing.T) {
+	text := "café au lait, thé à la menthe"
+	sa := NewSuffixArray(text)
+
+	for context := 0; context < 8; context++ {
+		for _, hit := range sa.KWIC("a", KWICOptions{Context: context}) {
+			for _, part := range []string{hit.Before, hit.After} {
+				if !utf8.ValidString(part) {
+					t.Fatalf("context %d around %d splits a rune: %q", context, hit.Position, part)
+				}
+			}
+			if text[hit.Position-len(hit.Before):hit.Position+len(hit.Match)+len(hit.After)] != hit.Before+hit.Match+hit.After {
+				t.Fatalf("context %d around %d is not contiguous", context, hit.Position)
+			}
+			if len(hit.Before) > context || len(hit.After) > context {
+				t.Fatalf("context %d around %d is too wide: %+v", context, hit.Position, hit)
+			}
+		}
+	}
+}
+
+func TestKWICLines(t *testing.T) {
+	text := "one\ntwo error\nthree\nfour\nfive error\nsix"
+	sa := NewSuffixArray(text)
+
+	tests := []struct {
+		context int
+		want    []string
+	}{
+		{0, []string{"two error", "five error"}},
+		{1, []string{"one\ntwo error\nthree", "four\nfive error\nsix"}},
+		{5, []string{text, text}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, hit := range sa.KWIC("error", KWICOptions{SearchOptions: SearchOptions{Sorted: true}, Context: tt.context, Lines: true}) {
+			got = append(got, hit.Before+hit.Match+hit.After)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("KWIC lines, context %d = %q, want %q", tt.context, got, tt.want)
+		}
+	}
+
+	// A match spanning lines brings all of them
+	hits := sa.KWIC("two error\nth", KWICOptions{Context: 0, Lines: true})
+	if len(hits) != 1 || hits[0].Before+hits[0].Match+hits[0].After != "two error\nthree" {
+		t.Errorf("KWIC of a multi-line match = %+v", hits)
+	}
+}
+
+func BenchmarkKWIC(b *testing.B) {
+	words := benchmarkWords()
+	text := strings.Join(words, " ")
+	sa := NewSuffixArray(text)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = sa.KWIC(words[i%len(words)][:2], KWICOptions{SearchOptions: SearchOptions{Limit: 20}, Context: 40})
+	}
+}
@@ -0,0 +1,201 @@
+package fuzzy
+
+import "sort"
+
+// LCP returns the longest common prefix array of the suffix array: entry i
+// is the length of the prefix shared by the suffixes at ranks i-1 and i, and
+// entry 0 is 0. It is computed once with Kasai's algorithm on first use and
+// must not be modified.
+func (sa *SuffixArray) LCP() []int {
+	sa.lcpOnce.Do(func() {
+		sa.lcp = kasai(sa.text, sa.suffixes)
+	})
+	return sa.lcp
+}
+
+// kasai computes the LCP array in linear time by visiting suffixes in text
+// order, where the shared prefix shrinks by at most one per step
+func kasai(text string, suffixes []int) []int {
+	n := len(suffixes)
+	lcp := make([]int, n)
+	rank := make([]int, n)
+	for i, s := range suffixes {
+		rank[s] = i
+	}
+
+	h := 0
+	for i := 0; i < n; i++ {
+		r := rank[i]
+		if r == 0 {
+			h = 0
+			continue
+		}
+
+		j := suffixes[r-1]
+		for i+h < n && j+h < n && text[i+h] == text[j+h] {
+			h++
+		}
+		lcp[r] = h
+
+		if h > 0 {
+			h--
+		}
+	}
+
+	return lcp
+}
+
+// LongestRepeatedSubstring returns the longest substring occurring at least
+// twice in the text, possibly overlapping, or "" when no byte repeats.
+// Among several of the same length the lexicographically smallest wins.
+func (sa *SuffixArray) LongestRepeatedSubstring() string {
+	lcp := sa.LCP()
+
+	best, rank := 0, 0
+	for i, l := range lcp {
+		if l > best {
+			best, rank = l, i
+		}
+	}
+
+	if best == 0 {
+		return ""
+	}
+	start := sa.suffixes[rank]
+	return sa.substring(start, start+best)
+}
+
+// Repeat is a substring occurring more than once in the text
+type Repeat struct {
+	Text  string
+	Count int // Number of occurrences, which may overlap
+}
+
+// Repeats returns the maximal repeats of at least minLen bytes, longest
+// first: substrings occurring more than once that cannot be extended to the
+// left or right without losing an occurrence. Duplicated boilerplate thus
+// shows up once rather than as each of its prefixes and suffixes. Use
+// AllRepeats for every repeated substring.
+func (sa *SuffixArray) Repeats(minLen int) []Repeat {
+	if minLen < 1 {
+		minLen = 1
+	}
+
+	// leftChanges[i] counts the ranks up to i whose preceding byte differs
+	// from that of the previous rank; the suffix starting the text has no
+	// preceding byte and differs from everything
+	n := len(sa.suffixes)
+	leftChanges := make([]int, n+1)
+	for i := 1; i < n; i++ {
+		leftChanges[i] = leftChanges[i-1]
+		a, b := sa.suffixes[i-1], sa.suffixes[i]
+		if a == 0 || b == 0 || sa.text[a-1] != sa.text[b-1] {
+			leftChanges[i]++
+		}
+	}
+
+	var repeats []Repeat
+	sa.lcpIntervals(func(lcp, parentLCP, lb, rb int) {
+		if lcp >= minLen && leftChanges[rb] > leftChanges[lb] {
+			start := sa.suffixes[lb]
+			repeats = append(repeats, Repeat{
+				Text:  sa.substring(start, start+lcp),
+				Count: rb - lb + 1,
+			})
+		}
+	})
+
+	sortRepeats(repeats)
+	return repeats
+}
+
+// AllRepeats returns every substring of at least minLen bytes occurring more
+// than once, with its number of occurrences, in the order of Repeats. Unlike
+// Repeats it includes substrings of longer repeats, such as "bc" of a
+// repeated "abcd", so the result can hold a number of repeats quadratic in
+// the text length; a run of n equal bytes has n-1 repeated substrings.
+func (sa *SuffixArray) AllRepeats(minLen int) []Repeat {
+	if minLen < 1 {
+		minLen = 1
+	}
+
+	// Each interval stands for the substrings of its suffixes' shared prefix
+	// longer than its parent's, which occur exactly rb-lb+1 times
+	var repeats []Repeat
+	sa.lcpIntervals(func(lcp, parentLCP, lb, rb int) {
+		if lcp < minLen {
+			return
+		}
+		start := sa.suffixes[lb]
+		text := sa.substring(start, start+lcp)
+		for length := maxInt(parentLCP+1, minLen); length <= lcp; length++ {
+			repeats = append(repeats, Repeat{
+				Text:  text[:length],
+				Count: rb - lb + 1,
+			})
+		}
+	})
+
+	sortRepeats(repeats)
+	return repeats
+}
+
+// lcpIntervals walks the LCP intervals bottom-up, calling visit for each
+// interval [lb, rb] of ranks whose suffixes share a prefix of exactly lcp
+// bytes, with the lcp value of its enclosing interval. The root interval
+// with value 0 is not visited.
+func (sa *SuffixArray) lcpIntervals(visit func(lcp, parentLCP, lb, rb int)) {
+	lcp := sa.LCP()
+	n := len(lcp)
+
+	type interval struct {
+		lcp, lb int
+	}
+	stack := []interval{{0, 0}}
+
+	for i := 1; i <= n; i++ {
+		cur := 0
+		if i < n {
+			cur = lcp[i]
+		}
+
+		lb := i - 1
+		for cur < stack[len(stack)-1].lcp {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			// The parent is the interval below on the stack, or the one
+			// about to be pushed for cur
+			parent := maxInt(stack[len(stack)-1].lcp, cur)
+			visit(top.lcp, parent, top.lb, i-1)
+			lb = top.lb
+		}
+
+		if cur > stack[len(stack)-1].lcp {
+			stack = append(stack, interval{cur, lb})
+		}
+	}
+}
+
+func sortRepeats(repeats []Repeat) {
+	sort.Slice(repeats, func(i, j int) bool {
+		if len(repeats[i].Text) != len(repeats[j].Text) {
+			return len(repeats[i].Text) > len(repeats[j].Text)
+		}
+		if repeats[i].Count != repeats[j].Count {
+			return repeats[i].Count > repeats[j].Count
+		}
+		return repeats[i].Text < repeats[j].Text
+	})
+}
+
+// DistinctSubstrings returns the number of distinct non-empty substrings of
+// the text
+func (sa *SuffixArray) DistinctSubstrings() int {
+	n := len(sa.text)
+	total := n * (n + 1) / 2
+	for _, l := range sa.LCP() {
+		total -= l
+	}
+	return total
+}
@@ -0,0 +1,190 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLCP(t *testing.T) {
+	sa := NewSuffixArray("banana")
+	// Suffixes in order: a, ana, anana, banana, na, nana
+	want := []int{0, 1, 3, 0, 0, 2}
+	if got := sa.LCP(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LCP() = %v, want %v", got, want)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		text := randomString(rng, rng.Intn(100), "abc")
+		sa := NewSuffixArray(text)
+		lcp := sa.LCP()
+		for i := 1; i < len(lcp); i++ {
+			a, b := text[sa.suffixes[i-1]:], text[sa.suffixes[i]:]
+			h := 0
+			for h < len(a) && h < len(b) && a[h] == b[h] {
+				h++
+			}
+			if lcp[i] != h {
+				t.Fatalf("LCP of %q at rank %d = %d, want %d", text, i, lcp[i], h)
+			}
+		}
+	}
+}
+
+func TestLongestRepeatedSubstring(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"abc", ""},
+		{"banana", "ana"},
+		{"abcabcabc", "abcabc"},
+		{"mississippi", "issi"},
+	}
+
+	for _, tt := range tests {
+		if got := NewSuffixArray(tt.text).LongestRepeatedSubstring(); got != tt.want {
+			t.Errorf("LongestRepeatedSubstring(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRepeats(t *testing.T) {
+	boilerplate := "Confidential: do not distribute."
+	text := "Report one. " + boilerplate + " Report two. " + boilerplate + " Report three. " + boilerplate
+
+	// The boilerplate is preceded by ". " each time, so that is the repeat
+	// reported for all three copies
+	want := ". " + boilerplate
+	found := false
+	for _, r := range NewSuffixArray(text).Repeats(10) {
+		if r.Count == 3 && strings.Contains(want, r.Text) {
+			if r.Text != want {
+				t.Errorf("Repeats reported %q, contained in the maximal repeat %q", r.Text, want)
+			}
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Repeats did not report %q three times", want)
+	}
+
+	got := NewSuffixArray("xabcyabcz").Repeats(1)
+	wantRepeats := []Repeat{{Text: "abc", Count: 2}}
+	if !reflect.DeepEqual(got, wantRepeats) {
+		t.Errorf("Repeats(1) = %+v, want %+v", got, wantRepeats)
+	}
+}
+
+func TestRepeatsMatchNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		text := randomString(rng, rng.Intn(40), "ab")
+
+		// A repeat is maximal when every extension by one byte on either
+		// side occurs fewer times
+		want := make(map[string]int)
+		for i := 0; i < len(text); i++ {
+			for j := i + 1; j <= len(text); j++ {
+				sub := text[i:j]
+				count := countOverlapping(text, sub)
+				if count < 2 {
+					break
+				}
+				maximal := true
+				for _, c := range "ab" {
+					if countOverlapping(text, string(c)+sub) == count || countOverlapping(text, sub+string(c)) == count {
+						maximal = false
+					}
+				}
+				if maximal {
+					want[sub] = count
+				}
+			}
+		}
+
+		got := make(map[string]int)
+		for _, r := range NewSuffixArray(text).Repeats(1) {
+			got[r.Text] = r.Count
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Repeats(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestAllRepeatsMatchNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		text := randomString(rng, rng.Intn(40), "abc")
+		minLen := 1 + rng.Intn(3)
+
+		want := make(map[string]int)
+		for i := 0; i < len(text); i++ {
+			for j := i + minLen; j <= len(text); j++ {
+				if count := countOverlapping(text, text[i:j]); count >= 2 {
+					want[text[i:j]] = count
+				}
+			}
+		}
+
+		repeats := NewSuffixArray(text).AllRepeats(minLen)
+		got := make(map[string]int)
+		for _, r := range repeats {
+			if _, dup := got[r.Text]; dup {
+				t.Fatalf("AllRepeats(%d) of %q reports %q twice", minLen, text, r.Text)
+			}
+			got[r.Text] = r.Count
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("AllRepeats(%d) of %q = %v, want %v", minLen, text, got, want)
+		}
+	}
+
+	// A repeat inside a longer one with the same count is reported too
+	got := NewSuffixArray("xabcdyabcdz").AllRepeats(3)
+	want := []Repeat{{Text: "abcd", Count: 2}, {Text: "abc", Count: 2}, {Text: "bcd", Count: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("AllRepeats(3) = %+v, want %+v", got, want)
+	}
+}
+
+func countOverlapping(text, sub string) int {
+	count := 0
+	for i := 0; i+len(sub) <= len(text); i++ {
+		if text[i:i+len(sub)] == sub {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDistinctSubstrings(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 100; iter++ {
+		text := randomString(rng, rng.Intn(30), "abc")
+
+		seen := make(map[string]bool)
+		for i := 0; i < len(text); i++ {
+			for j := i + 1; j <= len(text); j++ {
+				seen[text[i:j]] = true
+			}
+		}
+
+		if got := NewSuffixArray(text).DistinctSubstrings(); got != len(seen) {
+			t.Fatalf("DistinctSubstrings(%q) = %d, want %d", text, got, len(seen))
+		}
+	}
+}
+
+func BenchmarkLCP(b *testing.B) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		kasai(text, NewSuffixArray(text).suffixes)
+	}
+}
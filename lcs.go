@@ -0,0 +1,156 @@
+package fuzzy
+
+// CommonSubstring locates a substring shared by two strings: it is
+// s1[Start1:Start1+Length] and s2[Start2:Start2+Length]
+type CommonSubstring struct {
+	Start1 int
+	Start2 int
+	Length int
+}
+
+// lcsSuffixArrayThreshold is the size of the dynamic programming table above
+// which LongestCommonSubstring switches to a suffix array
+const lcsSuffixArrayThreshold = 1 << 20
+
+// LCSLength returns the length of the longest common subsequence of s1 and
+// s2, using two rows of memory
+func LCSLength(s1, s2 string) int {
+	if len(s1) < len(s2) {
+		s1, s2 = s2, s1
+	}
+	if len(s2) == 0 {
+		return 0
+	}
+
+	prev := make([]int, len(s2)+1)
+	curr := make([]int, len(s2)+1)
+
+	for i := 1; i <= len(s1); i++ {
+		for j := 1; j <= len(s2); j++ {
+			if s1[i-1] == s2[j-1] {
+				curr[j] = prev[j-1] + 1
+			} else {
+				curr[j] = maxInt(prev[j], curr[j-1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(s2)]
+}
+
+// LongestCommonSubsequence returns a longest sequence of bytes appearing in
+// both s1 and s2 in the same order, though not necessarily contiguously.
+// It keeps the full len(s1)*len(s2) table to recover the sequence.
+func LongestCommonSubsequence(s1, s2 string) string {
+	n := len(s1)
+	m := len(s2)
+	if n == 0 || m == 0 {
+		return ""
+	}
+
+	width := m + 1
+	table := make([]int, (n+1)*width)
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			k := i*width + j
+			if s1[i-1] == s2[j-1] {
+				table[k] = table[k-width-1] + 1
+			} else {
+				table[k] = maxInt(table[k-width], table[k-1])
+			}
+		}
+	}
+
+	result := make([]byte, table[n*width+m])
+	pos := len(result)
+	i, j := n, m
+	for pos > 0 {
+		k := i*width + j
+		switch {
+		case s1[i-1] == s2[j-1]:
+			pos--
+			result[pos] = s1[i-1]
+			i--
+			j--
+		case table[k-width] >= table[k-1]:
+			i--
+		default:
+			j--
+		}
+	}
+
+	return string(result)
+}
+
+// LongestCommonSubstring returns the longest contiguous substring shared by
+// s1 and s2, preferring the one that ends first in s1 when several exist.
+// Long inputs are matched against a suffix array of s1 instead of filling
+// a quadratic table, in which case ties may resolve differently.
+func LongestCommonSubstring(s1, s2 string) CommonSubstring {
+	if len(s1) == 0 || len(s2) == 0 {
+		return CommonSubstring{}
+	}
+	if len(s1)*len(s2) > lcsSuffixArrayThreshold {
+		return NewSuffixArray(s1).LongestCommonSubstring(s2)
+	}
+
+	prev := make([]int, len(s2)+1)
+	curr := make([]int, len(s2)+1)
+
+	var best CommonSubstring
+	for i := 1; i <= len(s1); i++ {
+		for j := 1; j <= len(s2); j++ {
+			if s1[i-1] != s2[j-1] {
+				curr[j] = 0
+				continue
+			}
+			curr[j] = prev[j-1] + 1
+			if curr[j] > best.Length {
+				best = CommonSubstring{
+					Start1: i - curr[j],
+					Start2: j - curr[j],
+					Length: curr[j],
+				}
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return best
+}
+
+// LongestCommonSubstring returns the longest substring shared by the indexed
+// text and other, with Start1 in the indexed text and Start2 in other. It
+// walks other once, extending each match through the suffix array.
+func (sa *SuffixArray) LongestCommonSubstring(other string) CommonSubstring {
+	var best CommonSubstring
+	length := 0
+
+	for j := 0; j < len(other); j++ {
+		// The previous match without its first byte still occurs
+		if length > 0 {
+			length--
+		}
+
+		lo, hi := sa.prefixRange(other[j : j+length])
+		for j+length < len(other) {
+			nlo, nhi := sa.narrow(lo, hi, length, other[j+length])
+			if nlo >= nhi {
+				break
+			}
+			lo, hi = nlo, nhi
+			length++
+		}
+
+		if length > best.Length {
+			best = CommonSubstring{
+				Start1: sa.suffixes[lo],
+				Start2: j,
+				Length: length,
+			}
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,125 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestLCSLength(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"", "", 0},
+		{"abc", "", 0},
+		{"abc", "abc", 3},
+		{"ABCBDAB", "BDCABA", 4},
+		{"AGGTAB", "GXTXAYB", 4},
+		{"abc", "def", 0},
+	}
+
+	for _, tt := range tests {
+		got := LCSLength(tt.s1, tt.s2)
+		if got != tt.want {
+			t.Errorf("LCSLength(%q, %q) = %d, want %d", tt.s1, tt.s2, got, tt.want)
+		}
+
+		seq := LongestCommonSubsequence(tt.s1, tt.s2)
+		if len(seq) != tt.want {
+			t.Errorf("LongestCommonSubsequence(%q, %q) = %q, want length %d", tt.s1, tt.s2, seq, tt.want)
+		}
+		if !isSubsequence(seq, tt.s1) || !isSubsequence(seq, tt.s2) {
+			t.Errorf("LongestCommonSubsequence(%q, %q) = %q is not a common subsequence", tt.s1, tt.s2, seq)
+		}
+	}
+}
+
+func TestLongestCommonSubstring(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   CommonSubstring
+	}{
+		{"", "abc", CommonSubstring{}},
+		{"abc", "xyz", CommonSubstring{}},
+		{"xabcdy", "zzabcd", CommonSubstring{Start1: 1, Start2: 2, Length: 4}},
+		{"GeeksforGeeks", "GeeksQuiz", CommonSubstring{Start1: 0, Start2: 0, Length: 5}},
+	}
+
+	for _, tt := range tests {
+		got := LongestCommonSubstring(tt.s1, tt.s2)
+		if got != tt.want {
+			t.Errorf("LongestCommonSubstring(%q, %q) = %+v, want %+v", tt.s1, tt.s2, got, tt.want)
+		}
+	}
+}
+
+func TestSuffixArrayLongestCommonSubstring(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		s1 := randomString(rng, rng.Intn(40), "abc")
+		s2 := randomString(rng, rng.Intn(40), "abc")
+
+		want := LongestCommonSubstring(s1, s2)
+		got := NewSuffixArray(s1).LongestCommonSubstring(s2)
+
+		if got.Length != want.Length {
+			t.Fatalf("LongestCommonSubstring(%q, %q) length = %d, want %d", s1, s2, got.Length, want.Length)
+		}
+		if s1[got.Start1:got.Start1+got.Length] != s2[got.Start2:got.Start2+got.Length] {
+			t.Fatalf("LongestCommonSubstring(%q, %q) = %+v does not match", s1, s2, got)
+		}
+	}
+}
+
+func TestLongestCommonSubstringLongInput(t *testing.T) {
+	shared := "duplicated boilerplate paragraph"
+	s1 := strings.Repeat("lorem ipsum ", 200) + shared + strings.Repeat(" dolor", 10)
+	s2 := strings.Repeat("sit amet ", 150) + shared
+
+	got := LongestCommonSubstring(s1, s2)
+	if got.Length < len(shared) {
+		t.Fatalf("Length = %d, want at least %d", got.Length, len(shared))
+	}
+	if s1[got.Start1:got.Start1+got.Length] != s2[got.Start2:got.Start2+got.Length] {
+		t.Errorf("result %+v does not match in both strings", got)
+	}
+}
+
+func isSubsequence(sub, s string) bool {
+	i := 0
+	for j := 0; j < len(s) && i < len(sub); j++ {
+		if sub[i] == s[j] {
+			i++
+		}
+	}
+	return i == len(sub)
+}
+
+func randomString(rng *rand.Rand, n int, alphabet string) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+func BenchmarkLongestCommonSubsequence(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		LongestCommonSubsequence(s1, s2)
+	}
+}
+
+func BenchmarkLongestCommonSubstring(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		LongestCommonSubstring(s1, s2)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !unix
+
+package fuzzy
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the whole file into memory on platforms without mmap, so
+// loading costs time and heap memory proportional to its size
+func mapFile(f *os.File) (*mapping, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, info.Size())
+	_, err = io.ReadFull(io.NewSectionReader(f, 0, info.Size()), data)
+	if err != nil {
+		return nil, err
+	}
+	return &mapping{data: data}, nil
+}
+
+// mapWorkFile allocates working memory on platforms without mmap, where
+// working arrays cannot be paged to disk
+func mapWorkFile(path string, size int) (*mapping, error) {
+	return &mapping{data: make([]byte, size)}, nil
+}
+
+func (m *mapping) unmap() error {
+	return nil
+}
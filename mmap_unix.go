@@ -0,0 +1,56 @@
+//go:build unix
+
+package fuzzy
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the whole file read-only into memory, shared with every
+// other process mapping it
+func mapFile(f *os.File) (*mapping, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return &mapping{}, nil
+	}
+	if int64(int(size)) != size {
+		return nil, errFileTooLarge
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return &mapping{data: data, mapped: true}, nil
+}
+
+func (m *mapping) unmap() error {
+	return syscall.Munmap(m.data)
+}
+
+// mapWorkFile creates a temporary file of size bytes and maps it writable,
+// so that large working arrays are paged to disk instead of held on the heap
+func mapWorkFile(path string, size int) (*mapping, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if size == 0 {
+		return &mapping{}, nil
+	}
+	if err := f.Truncate(int64(size)); err != nil {
+		return nil, err
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return &mapping{data: data, mapped: true}, nil
+}
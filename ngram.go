@@ -15,6 +15,7 @@ type NGram struct {
 	grams      map[string][]int
 	corpus     []string
 	corpusSize []int // Store corpus text sizes for better scoring
+	gramCounts []int // Number of distinct n-grams of each document
 	mu         sync.RWMutex
 	
 	// Options
@@ -28,6 +29,7 @@ func NewNGram(n int) *NGram {
 		grams:      make(map[string][]int),
 		corpus:     make([]string, 0, 1024),
 		corpusSize: make([]int, 0, 1024),
+		gramCounts: make([]int, 0, 1024),
 		normalize:  true, // Default to true for better matching
 	}
 }
@@ -53,10 +55,9 @@ func (ng *NGram) Add(text string, id int) {
 		processedText = strings.ToLower(text)
 	}
 	
-	// Generate n-grams
-	grams := ng.generateNGramsFast(processedText)
-	
-	// Add to index without deduplication for speed
+	// Generate n-grams, posting each distinct one once
+	grams := distinctGrams(ng.generateNGramsFast(processedText))
+	ng.gramCounts = append(ng.gramCounts, len(grams))
 	for _, gram := range grams {
 		ng.grams[gram] = append(ng.grams[gram], id)
 	}
@@ -125,14 +126,32 @@ func (ng *NGram) generateNGramsFast(text string) []string {
 	return grams
 }
 
-// NGramResult contains search result with additional metadata
+// distinctGrams removes repeated n-grams in place, keeping the first of each
+func distinctGrams(grams []string) []string {
+	seen := make(map[string]struct{}, len(grams))
+	out := grams[:0]
+	for _, gram := range grams {
+		if _, ok := seen[gram]; !ok {
+			seen[gram] = struct{}{}
+			out = append(out, gram)
+		}
+	}
+	return out
+}
+
+// NGramResult contains search result with additional metadata.
+// Score is a similarity in [0, 1]; see Search and SearchWithDistance for how
+// each method computes it.
 type NGramResult struct {
 	ID    int
 	Score float64
 	Text  string
 }
 
-// Search performs optimized search with better scoring
+// Search performs optimized search with better scoring. The score is the
+// Dice coefficient of the distinct n-grams of the query and the document,
+// twice the number they share over the sum of their counts, so it is 1 only
+// when both have the same n-grams.
 func (ng *NGram) Search(query string, threshold float64) []NGramResult {
 	ng.mu.RLock()
 	defer ng.mu.RUnlock()
@@ -145,12 +164,12 @@ func (ng *NGram) Search(query string, threshold float64) []NGramResult {
 		processedQuery = strings.ToLower(query)
 	}
 	
-	queryGrams := ng.generateNGramsFast(processedQuery)
+	queryGrams := distinctGrams(ng.generateNGramsFast(processedQuery))
 	if len(queryGrams) == 0 {
 		return nil
 	}
 	
-	// Count matching grams per document
+	// Count shared distinct grams per document
 	candidates := make(map[int]int, 32)
 	
 	for _, gram := range queryGrams {
@@ -163,10 +182,9 @@ func (ng *NGram) Search(query string, threshold float64) []NGramResult {
 	
 	// Calculate scores
 	results := make([]NGramResult, 0, len(candidates))
-	queryLen := float64(len(queryGrams))
 	
-	for id, matchCount := range candidates {
-		score := float64(matchCount) / queryLen
+	for id, shared := range candidates {
+		score := 2 * float64(shared) / float64(len(queryGrams)+ng.gramCounts[id])
 		
 		if score >= threshold {
 			results = append(results, NGramResult{
@@ -217,8 +235,8 @@ func (ng *NGram) BatchAdd(texts []string) {
 			processedText = strings.ToLower(text)
 		}
 		
-		grams := ng.generateNGramsFast(processedText)
-		
+		grams := distinctGrams(ng.generateNGramsFast(processedText))
+		ng.gramCounts = append(ng.gramCounts, len(grams))
 		for _, gram := range grams {
 			ng.grams[gram] = append(ng.grams[gram], startID+i)
 		}
@@ -240,6 +258,7 @@ func (ng *NGram) Clear() {
 	ng.grams = make(map[string][]int)
 	ng.corpus = ng.corpus[:0]
 	ng.corpusSize = ng.corpusSize[:0]
+	ng.gramCounts = ng.gramCounts[:0]
 }
 
 // Helper functions
@@ -272,7 +291,8 @@ func NewTrigramIndex() *TrigramIndex {
 	}
 }
 
-// SearchWithDistance searches using edit distance filtering
+// SearchWithDistance searches using edit distance filtering and scores each
+// result with LevenshteinSimilarity
 func (ti *TrigramIndex) SearchWithDistance(query string, maxDistance int) []NGramResult {
 	// Use trigram similarity to filter candidates
 	minSimilarity := 1.0 - float64(maxDistance)*0.3
@@ -280,11 +300,11 @@ func (ti *TrigramIndex) SearchWithDistance(query string, maxDistance int) []NGra
 	
 	// Filter by actual edit distance
 	results := make([]NGramResult, 0, len(candidates))
+	compiled := CompileQuery(query)
 	for _, candidate := range candidates {
-		dist := LevenshteinDistance(query, candidate.Text)
+		dist := compiled.Distance(candidate.Text)
 		if dist <= maxDistance {
-			// Update score based on edit distance
-			candidate.Score = 1.0 - float64(dist)/float64(maxInt(len(query), len(candidate.Text)))
+			candidate.Score = normalizeDistance(dist, MaxLengthBound(query, candidate.Text))
 			results = append(results, candidate)
 		}
 	}
@@ -385,6 +405,25 @@ func (qg *QGram) Distance(other *QGram) float64 {
 	return distance
 }
 
+// Similarity normalizes Distance into [0, 1] by dividing it by the total
+// number of q-grams in both profiles, so identical profiles score 1 and
+// profiles without shared q-grams score 0
+func (qg *QGram) Similarity(other *QGram) float64 {
+	total := 0.0
+	for _, count := range qg.grams {
+		total += count
+	}
+	for _, count := range other.grams {
+		total += count
+	}
+	
+	if total == 0 {
+		return 1
+	}
+	
+	return 1 - qg.Distance(other)/total
+}
+
 // CosineSimilarity calculates cosine similarity between q-gram profiles
 func (qg *QGram) CosineSimilarity(other *QGram) float64 {
 	dotProduct := 0.0
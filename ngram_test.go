@@ -2,6 +2,7 @@ package fuzzy
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 )
@@ -95,6 +96,41 @@ func TestNGramBatchAdd(t *testing.T) {
 	}
 }
 
+func TestNGramSearchScores(t *testing.T) {
+	ng := NewNGram(3)
+	ng.BatchAdd([]string{"aaaaaa", "aaab", "xaaabx yz"})
+
+	// Repeated grams count once, and only the identical text scores 1
+	want := map[string]float64{
+		"aaaaaa":    2.0 / 3,
+		"aaab":      1,
+		"xaaabx yz": 4.0 / 9,
+	}
+	results := ng.Search("aaab", 0)
+	if len(results) != len(want) {
+		t.Fatalf("Search returned %d results, want %d", len(results), len(want))
+	}
+	for _, r := range results {
+		if math.Abs(r.Score-want[r.Text]) > 1e-9 {
+			t.Errorf("Score(%q) = %v, want %v", r.Text, r.Score, want[r.Text])
+		}
+	}
+	if results[0].Text != "aaab" {
+		t.Errorf("best result = %q, want aaab", results[0].Text)
+	}
+
+	// The score is symmetric, as the package's similarities are
+	for _, pair := range [][2]string{{"aaab", "xaaabx yz"}, {"hello world", "world"}} {
+		forward, backward := NewNGram(3), NewNGram(3)
+		forward.Add(pair[1], 0)
+		backward.Add(pair[0], 0)
+		a, b := forward.Search(pair[0], 0), backward.Search(pair[1], 0)
+		if len(a) != 1 || len(b) != 1 || math.Abs(a[0].Score-b[0].Score) > 1e-9 {
+			t.Errorf("scores of %q and %q are not symmetric: %v, %v", pair[0], pair[1], a, b)
+		}
+	}
+}
+
 func TestTrigramIndex(t *testing.T) {
 	ti := NewTrigramIndex()
 	
@@ -0,0 +1,226 @@
+package fuzzy
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NormalizeOptions selects the transformations applied by Normalize and
+// NewNormalizedSuffixArray
+type NormalizeOptions struct {
+	// FoldCase maps every letter to lower case
+	FoldCase bool
+
+	// RemoveDiacritics maps accented Latin letters such as "é" to their base
+	// letter and drops combining marks
+	RemoveDiacritics bool
+
+	// CollapseSpace replaces each run of Unicode white space with one space
+	CollapseSpace bool
+}
+
+// diacriticBases lists the accented Latin letters mapped to each base letter
+var diacriticBases = []struct {
+	base    rune
+	letters string
+}{
+	{'A', "ÀÁÂÃÄÅĀĂĄǍǞǠǺȀȂȦ"}, {'a', "àáâãäåāăąǎǟǡǻȁȃȧ"},
+	{'C', "ÇĆĈĊČ"}, {'c', "çćĉċč"},
+	{'D', "ĎĐ"}, {'d', "ďđ"},
+	{'E', "ÈÉÊËĒĔĖĘĚȄȆȨ"}, {'e', "èéêëēĕėęěȅȇȩ"},
+	{'G', "ĜĞĠĢǦǴ"}, {'g', "ĝğġģǧǵ"},
+	{'H', "ĤĦȞ"}, {'h', "ĥħȟ"},
+	{'I', "ÌÍÎÏĨĪĬĮİǏȈȊ"}, {'i', "ìíîïĩīĭįıǐȉȋ"},
+	{'J', "Ĵ"}, {'j', "ĵǰ"},
+	{'K', "ĶǨ"}, {'k', "ķǩ"},
+	{'L', "ĹĻĽĿŁ"}, {'l', "ĺļľŀł"},
+	{'N', "ÑŃŅŇǸ"}, {'n', "ñńņňǹ"},
+	{'O', "ÒÓÔÕÖØŌŎŐƠǑǪǬȌȎȪȬȮȰ"}, {'o', "òóôõöøōŏőơǒǫǭȍȏȫȭȯȱ"},
+	{'R', "ŔŖŘȐȒ"}, {'r', "ŕŗřȑȓ"},
+	{'S', "ŚŜŞŠȘ"}, {'s', "śŝşšș"},
+	{'T', "ŢŤŦȚ"}, {'t', "ţťŧț"},
+	{'U', "ÙÚÛÜŨŪŬŮŰŲƯǓǕǗǙǛȔȖ"}, {'u', "ùúûüũūŭůűųưǔǖǘǚǜȕȗ"},
+	{'W', "Ŵ"}, {'w', "ŵ"},
+	{'Y', "ÝŶŸȲ"}, {'y', "ýÿŷȳ"},
+	{'Z', "ŹŻŽ"}, {'z', "źżž"},
+}
+
+var diacriticTable = func() map[rune]rune {
+	table := make(map[rune]rune)
+	for _, d := range diacriticBases {
+		for _, r := range d.letters {
+			table[r] = d.base
+		}
+	}
+	return table
+}()
+
+// Normalize returns text transformed as selected by opts
+func Normalize(text string, opts NormalizeOptions) string {
+	normalized, _ := normalize(text, opts, false)
+	return string(normalized)
+}
+
+// normalize transforms text and, when withOffsets is set, also returns for
+// each normalized byte the offset of the original rune it came from,
+// followed by len(text). Invalid UTF-8 bytes are copied unchanged.
+func normalize(text string, opts NormalizeOptions, withOffsets bool) ([]byte, []int) {
+	out := make([]byte, 0, len(text))
+	var offsets []int
+	if withOffsets {
+		offsets = make([]int, 0, len(text)+1)
+	}
+
+	inSpace := false
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		start := i
+		i += size
+
+		if r == utf8.RuneError && size == 1 {
+			inSpace = false
+			out = append(out, text[start])
+			if withOffsets {
+				offsets = append(offsets, start)
+			}
+			continue
+		}
+
+		if opts.CollapseSpace && unicode.IsSpace(r) {
+			if inSpace {
+				continue
+			}
+			inSpace = true
+			r = ' '
+		} else {
+			inSpace = false
+		}
+
+		if opts.RemoveDiacritics {
+			if unicode.Is(unicode.Mn, r) {
+				continue
+			}
+			if base, ok := diacriticTable[r]; ok {
+				r = base
+			}
+		}
+		if opts.FoldCase {
+			r = unicode.ToLower(r)
+		}
+
+		before := len(out)
+		out = utf8.AppendRune(out, r)
+		if withOffsets {
+			for j := before; j < len(out); j++ {
+				offsets = append(offsets, start)
+			}
+		}
+	}
+
+	if withOffsets {
+		offsets = append(offsets, len(text))
+	}
+	return out, offsets
+}
+
+// NormalizedSuffixArray is a suffix array over a normalized view of a text,
+// so searches can ignore case, accents or spacing. Matches are reported as
+// byte ranges of the original text.
+type NormalizedSuffixArray struct {
+	text string
+	opts NormalizeOptions
+	sa   *SuffixArray
+
+	// offsets maps each byte of the normalized text to the offset of the
+	// original rune it came from, with len(text) appended
+	offsets []int
+}
+
+// NewNormalizedSuffixArray builds a suffix array over text normalized with
+// opts, keeping the offsets of the normalized bytes in text
+func NewNormalizedSuffixArray(text string, opts NormalizeOptions) *NormalizedSuffixArray {
+	normalized, offsets := normalize(text, opts, true)
+	return &NormalizedSuffixArray{
+		text:    text,
+		opts:    opts,
+		sa:      NewSuffixArray(string(normalized)),
+		offsets: offsets,
+	}
+}
+
+// Text returns the original text
+func (n *NormalizedSuffixArray) Text() string {
+	return n.text
+}
+
+// Search returns the ranges of the original text whose normalized form
+// contains the normalized pattern, ordered by Start
+func (n *NormalizedSuffixArray) Search(pattern string) []Match {
+	normalized, _ := normalize(pattern, n.opts, false)
+	if len(normalized) == 0 {
+		return nil
+	}
+
+	positions := n.sa.Search(string(normalized))
+	matches := make([]Match, len(positions))
+	for i, p := range positions {
+		matches[i] = n.original(p, p+len(normalized), 0)
+	}
+	return n.dedupe(matches)
+}
+
+// FuzzySearch finds the ranges of the original text whose normalized form
+// is within maxErrors edits of the normalized pattern, with the semantics
+// of SuffixArray.FuzzySearch. Distances are counted in normalized bytes.
+func (n *NormalizedSuffixArray) FuzzySearch(pattern string, maxErrors int) []Match {
+	normalized, _ := normalize(pattern, n.opts, false)
+	found := n.sa.FuzzySearch(string(normalized), maxErrors)
+	matches := make([]Match, len(found))
+	for i, m := range found {
+		matches[i] = n.original(m.Start, m.End, m.Distance)
+	}
+	return n.dedupe(matches)
+}
+
+// original maps the normalized range [start, end) to the smallest range of
+// whole runes of the original text producing it
+func (n *NormalizedSuffixArray) original(start, end, distance int) Match {
+	m := Match{Start: n.offsets[start], End: n.offsets[start], Distance: distance}
+	if end > start {
+		// The last byte's rune ends where the next rune's bytes begin
+		last := n.offsets[end-1]
+		for n.offsets[end] == last {
+			end++
+		}
+		m.End = n.offsets[end]
+	}
+	return m
+}
+
+// dedupe sorts matches by Start and keeps one per Start, the one with the
+// smallest distance and then the longest range. Several normalized starts
+// map to one original rune when it expands to more than one byte.
+func (n *NormalizedSuffixArray) dedupe(matches []Match) []Match {
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		return a.End > b.End
+	})
+
+	out := matches[:0]
+	for _, m := range matches {
+		if len(out) == 0 || out[len(out)-1].Start != m.Start {
+			out = append(out, m)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
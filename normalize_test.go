@@ -0,0 +1,141 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	all := NormalizeOptions{FoldCase: true, RemoveDiacritics: true, CollapseSpace: true}
+
+	tests := []struct {
+		text string
+		opts NormalizeOptions
+		want string
+	}{
+		{"Connection REFUSED", NormalizeOptions{FoldCase: true}, "connection refused"},
+		{"Café Crème", NormalizeOptions{RemoveDiacritics: true}, "Cafe Creme"},
+		{"Café", NormalizeOptions{RemoveDiacritics: true}, "Cafe"},
+		{"a \t\n b", NormalizeOptions{CollapseSpace: true}, "a b"},
+		{"  Ÿes  ÅNGSTRÖM ", all, " yes angstrom "},
+		{"Łódź", all, "lodz"},
+		{"ÉCOLE", NormalizeOptions{}, "ÉCOLE"},
+		{"bad \xff byte", all, "bad \xff byte"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.text, tt.opts); got != tt.want {
+			t.Errorf("Normalize(%q, %+v) = %q, want %q", tt.text, tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizedSuffixArraySearch(t *testing.T) {
+	all := NormalizeOptions{FoldCase: true, RemoveDiacritics: true, CollapseSpace: true}
+
+	tests := []struct {
+		text    string
+		opts    NormalizeOptions
+		pattern string
+		want    []string
+	}{
+		{"Error: disk full. error: retry. ERROR", NormalizeOptions{FoldCase: true}, "error", []string{"Error", "error", "ERROR"}},
+		{"Café, cafe, CAFÉ", all, "cafe", []string{"Café", "cafe", "CAFÉ"}},
+		{"Café au lait", all, "café", []string{"Café"}},
+		{"connection \t refused\n\nconnection refused", all, "connection  refused", []string{"connection \t refused", "connection refused"}},
+		{"word   ", NormalizeOptions{CollapseSpace: true}, "d ", []string{"d   "}},
+		{"xȺB", NormalizeOptions{FoldCase: true}, "ⱥ", []string{"Ⱥ"}}, // 2 bytes fold to 3
+		{"Straße", NormalizeOptions{FoldCase: true}, "STRASSE", nil},
+		{"abc", all, "", nil},
+	}
+
+	for _, tt := range tests {
+		nsa := NewNormalizedSuffixArray(tt.text, tt.opts)
+		var got []string
+		for _, m := range nsa.Search(tt.pattern) {
+			got = append(got, tt.text[m.Start:m.End])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) in %q = %q, want %q", tt.pattern, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizedSuffixArrayRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "A", "á", "Á", "b", "B", " ", "\t", "é"}
+	opts := NormalizeOptions{FoldCase: true, RemoveDiacritics: true, CollapseSpace: true}
+
+	for iter := 0; iter < 200; iter++ {
+		var sb strings.Builder
+		for i := rng.Intn(60); i > 0; i-- {
+			sb.WriteString(alphabet[rng.Intn(len(alphabet))])
+		}
+		text := sb.String()
+		nsa := NewNormalizedSuffixArray(text, opts)
+		normalized := Normalize(text, opts)
+
+		pattern := Normalize(randomString(rng, 1+rng.Intn(3), "ab "), opts)
+		matches := nsa.Search(pattern)
+
+		if want := countOverlapping(normalized, pattern); len(matches) != want {
+			t.Fatalf("Search(%q) in %q found %d matches, want %d", pattern, text, len(matches), want)
+		}
+		for i, m := range matches {
+			if got := Normalize(text[m.Start:m.End], opts); got != pattern {
+				t.Fatalf("Search(%q) in %q: range %q normalizes to %q", pattern, text, text[m.Start:m.End], got)
+			}
+			if i > 0 && matches[i-1].Start >= m.Start {
+				t.Fatalf("Search(%q) in %q: matches not ordered by Start", pattern, text)
+			}
+		}
+	}
+}
+
+func TestNormalizedSuffixArrayFuzzySearch(t *testing.T) {
+	text := "Résumé sent. RESUME received. Resmue lost."
+	nsa := NewNormalizedSuffixArray(text, NormalizeOptions{FoldCase: true, RemoveDiacritics: true})
+
+	var got []string
+	for _, m := range nsa.FuzzySearch("resume", 1) {
+		if m.Distance == 0 {
+			got = append(got, text[m.Start:m.End])
+		}
+	}
+	want := []string{"Résumé", "RESUME"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("exact fuzzy matches = %q, want %q", got, want)
+	}
+
+	for _, m := range nsa.FuzzySearch("resume", 2) {
+		if strings.HasPrefix(text[m.Start:], "Resmue") && m.Distance == 2 {
+			return
+		}
+	}
+	t.Errorf("FuzzySearch(resume, 2) misses the transposed Resmue")
+}
+
+func BenchmarkNormalizedSuffixArray(b *testing.B) {
+	words := benchmarkWords()
+	text := strings.ToUpper(strings.Join(words, "  "))
+
+	b.Run("Build", func(b *testing.B) {
+		b.SetBytes(int64(len(text)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = NewNormalizedSuffixArray(text, NormalizeOptions{FoldCase: true, CollapseSpace: true})
+		}
+	})
+
+	nsa := NewNormalizedSuffixArray(text, NormalizeOptions{FoldCase: true, CollapseSpace: true})
+	b.Run("Search", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = nsa.Search(words[i%len(words)])
+		}
+	})
+}
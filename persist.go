@@ -0,0 +1,587 @@
+package fuzzy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// On-disk formats
+//
+// All integers are little-endian and every section starts at a multiple of
+// 8 bytes, so a mapped file can back the in-memory slices directly on
+// 64-bit little-endian hosts. Other hosts decode the sections into memory.
+//
+// Suffix array, version 1:
+//
+//	offset  size  field
+//	0       8     magic "FZSUFARR"
+//	8       4     format version (1)
+//	12      4     suffix array entry width in bytes (4 or 8)
+//	16      8     text length n
+//	24      n     text, zero-padded to a multiple of 8 bytes
+//	...     n*w   suffix array entries, as unsigned integers of width w
+//
+// FM-index, version 1:
+//
+//	offset  size  field
+//	0       8     magic "FZFMINDX"
+//	8       4     format version (1)
+//	12      4     reserved (0)
+//	16      8     number of BWT rows, the text length plus one
+//	24      8     row of the sentinel in the BWT
+//	32      8     sample rate
+//	40      2056  first row of each byte, 257 entries
+//	2096    64    number of zero bits in each wavelet matrix level
+//	...           8 wavelet matrix levels, then the sampled row bit vector,
+//	              each as its 64-bit words followed by one cumulative count
+//	              per 8 words plus a final one
+//	...     8     number of sampled rows s
+//	...     8*s   text position of each sampled row
+//	...     8     number of inverse suffix array samples t
+//	...     8*t   row of every sample-rate-th text position
+
+const (
+	suffixArrayMagic = "FZSUFARR"
+	fmIndexMagic     = "FZFMINDX"
+	formatVersion    = 1
+)
+
+var errFileTooLarge = errors.New("index file is too large to map on this platform")
+
+// mapping holds the file contents backing an opened index
+type mapping struct {
+	data   []byte
+	mapped bool
+}
+
+func (m *mapping) close() error {
+	if m == nil || !m.mapped {
+		return nil
+	}
+	m.mapped = false
+	return m.unmap()
+}
+
+// Save writes the suffix array and its text to path with 64-bit entries,
+// which OpenSuffixArray maps without copying. An existing file is replaced
+// only once the new one is complete, so processes that opened it can keep
+// using it.
+func (sa *SuffixArray) Save(path string) error {
+	return sa.save(path, 8)
+}
+
+// SaveCompact is Save with 32-bit entries when the text is shorter than
+// 4GiB, halving the file size. OpenSuffixArray decodes such files into
+// memory instead of mapping them.
+func (sa *SuffixArray) SaveCompact(path string) error {
+	if uint64(len(sa.text)) > math.MaxUint32 {
+		return sa.save(path, 8)
+	}
+	return sa.save(path, 4)
+}
+
+func (sa *SuffixArray) save(path string, width int) error {
+	return writeFile(path, func(w *bufio.Writer) error {
+		writeSuffixArrayHeader(w, len(sa.text), width)
+		w.WriteString(sa.text)
+		writePadding(w, len(sa.text))
+		for _, s := range sa.suffixes {
+			writeUint(w, uint64(s), width)
+		}
+		return nil
+	})
+}
+
+func writeSuffixArrayHeader(w *bufio.Writer, n, width int) {
+	w.WriteString(suffixArrayMagic)
+	writeUint(w, formatVersion, 4)
+	writeUint(w, uint64(width), 4)
+	writeUint(w, uint64(n), 8)
+}
+
+// OpenSuffixArray loads a suffix array written by Save or SaveCompact. The
+// file is memory-mapped where supported, so it loads in constant time and
+// its pages are shared between processes; call Close when done with it.
+// Only the header and section sizes are checked: the entries are trusted,
+// so call Verify before searching a file from an untrusted source.
+func OpenSuffixArray(path string) (*SuffixArray, error) {
+	m, err := openMapping(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sa, err := decodeSuffixArray(m.data)
+	if err != nil {
+		m.close()
+		return nil, fmt.Errorf("open suffix array %s: %w", path, err)
+	}
+	sa.mapping = m
+	return sa, nil
+}
+
+func decodeSuffixArray(data []byte) (*SuffixArray, error) {
+	d := &decoder{data: data}
+	d.magic(suffixArrayMagic)
+	width := d.uint(4)
+	n := d.length()
+	if d.err == nil && width != 4 && width != 8 {
+		d.err = fmt.Errorf("invalid suffix array entry width %d", width)
+	}
+
+	text := d.string(n)
+	d.align()
+
+	var suffixes []int
+	if width == 8 {
+		suffixes = d.ints(n)
+	} else {
+		suffixes = d.narrowInts(n)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return &SuffixArray{text: text, suffixes: suffixes}, nil
+}
+
+// Verify checks that the entries form the suffix array of the text, in
+// linear time and with one int of memory per text byte
+func (sa *SuffixArray) Verify() error {
+	n := len(sa.text)
+	if len(sa.suffixes) != n {
+		return fmt.Errorf("suffix array has %d entries for %d text bytes", len(sa.suffixes), n)
+	}
+
+	// rank[p] is one more than the rank of the suffix at p, and 0 for the
+	// empty suffix at n, which sorts first
+	rank := make([]int, n+1)
+	for r, p := range sa.suffixes {
+		if p < 0 || p >= n || rank[p] != 0 {
+			return fmt.Errorf("entry %d (%d) is out of range or repeated", r, p)
+		}
+		rank[p] = r + 1
+	}
+
+	// Adjacent suffixes are ordered by their first byte, then by the
+	// suffixes following it
+	for r := 1; r < n; r++ {
+		a, b := sa.suffixes[r-1], sa.suffixes[r]
+		if sa.text[a] > sa.text[b] || sa.text[a] == sa.text[b] && rank[a+1] > rank[b+1] {
+			return fmt.Errorf("entries %d and %d are out of order", r-1, r)
+		}
+	}
+	return nil
+}
+
+// substring returns text[i:j], copied out of a mapped file so that it stays
+// valid after Close
+func (sa *SuffixArray) substring(i, j int) string {
+	if sa.mapping != nil && sa.mapping.mapped {
+		return strings.Clone(sa.text[i:j])
+	}
+	return sa.text[i:j]
+}
+
+// Close releases the file backing a suffix array loaded by
+// OpenSuffixArray. The suffix array must not be used afterwards, but the
+// strings returned by its methods remain valid.
+func (sa *SuffixArray) Close() error {
+	return sa.mapping.close()
+}
+
+// Save writes the FM-index to path. OpenFMIndex maps it without copying.
+// Like SuffixArray.Save it replaces an existing file only once complete.
+func (fm *FMIndex) Save(path string) error {
+	return writeFile(path, func(w *bufio.Writer) error {
+		w.WriteString(fmIndexMagic)
+		writeUint(w, formatVersion, 4)
+		writeUint(w, 0, 4)
+		writeUint(w, uint64(fm.bwt.n), 8)
+		writeUint(w, uint64(fm.primary), 8)
+		writeUint(w, uint64(fm.sampleRate), 8)
+		writeInts(w, fm.counts[:])
+		writeInts(w, fm.bwt.zeros[:])
+		for _, level := range fm.bwt.levels {
+			writeBitVector(w, level)
+		}
+		writeBitVector(w, fm.sampled)
+		writeUint(w, uint64(len(fm.samplePos)), 8)
+		writeInts(w, fm.samplePos)
+		writeUint(w, uint64(len(fm.isaSamples)), 8)
+		writeInts(w, fm.isaSamples)
+		return nil
+	})
+}
+
+// OpenFMIndex loads an FM-index written by FMIndex.Save, memory-mapping the
+// file where supported; call Close when done with it
+func OpenFMIndex(path string) (*FMIndex, error) {
+	m, err := openMapping(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fm, err := decodeFMIndex(m.data)
+	if err != nil {
+		m.close()
+		return nil, fmt.Errorf("open FM-index %s: %w", path, err)
+	}
+	fm.mapping = m
+	return fm, nil
+}
+
+func decodeFMIndex(data []byte) (*FMIndex, error) {
+	d := &decoder{data: data}
+	d.magic(fmIndexMagic)
+	d.uint(4)
+	rows := d.length()
+	primary := d.length()
+	sampleRate := d.length()
+
+	fm := &FMIndex{
+		bwt:        &waveletMatrix{n: rows},
+		primary:    primary,
+		sampleRate: sampleRate,
+	}
+	copy(fm.counts[:], d.ints(len(fm.counts)))
+	copy(fm.bwt.zeros[:], d.ints(len(fm.bwt.zeros)))
+	for level := range fm.bwt.levels {
+		fm.bwt.levels[level] = d.bitVector(rows)
+	}
+	fm.sampled = d.bitVector(rows)
+	fm.samplePos = d.ints(d.length())
+	fm.isaSamples = d.ints(d.length())
+
+	if d.err == nil && (rows < 1 || sampleRate < 1 || primary >= rows) {
+		d.err = errors.New("invalid FM-index header")
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := fm.validate(); err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// validate checks the invariants of a decoded FM-index, so that a corrupt
+// file fails to open instead of making later queries panic or loop. It
+// reads the bit vectors once, about 9 bits per text byte, and every sample.
+// The BWT bytes themselves are trusted, so a file that passes may still
+// answer queries wrongly.
+func (fm *FMIndex) validate() error {
+	rows := fm.bwt.n
+	for level, bv := range fm.bwt.levels {
+		if !validBitVector(bv) || fm.bwt.zeros[level] != bv.rank0(rows) {
+			return fmt.Errorf("inconsistent wavelet matrix level %d", level)
+		}
+	}
+	if !validBitVector(fm.sampled) {
+		return errors.New("inconsistent sampled row bit vector")
+	}
+
+	if fm.counts[0] != 1 || fm.counts[256] != rows {
+		return errors.New("invalid byte counts")
+	}
+	for c := 0; c < 256; c++ {
+		if fm.counts[c+1]-fm.counts[c] != fm.occ(byte(c), rows) {
+			return fmt.Errorf("byte count of %d does not match the BWT", c)
+		}
+	}
+	if fm.bwt.access(fm.primary) != 0 {
+		return errors.New("sentinel row does not hold the sentinel")
+	}
+
+	if len(fm.isaSamples) != (rows-1)/fm.sampleRate+1 {
+		return errors.New("wrong number of inverse suffix array samples")
+	}
+	if len(fm.samplePos) != fm.sampled.rank1(rows) {
+		return errors.New("wrong number of sampled positions")
+	}
+	for _, pos := range fm.samplePos {
+		if pos < 0 || pos >= rows {
+			return fmt.Errorf("sampled position %d is out of range", pos)
+		}
+	}
+
+	// Every sample row is marked sampled, and text position 0 is in the
+	// sentinel row, which ends each walk back through the LF mapping
+	for j, row := range fm.isaSamples {
+		if row < 0 || row >= rows || !fm.sampled.get(row) {
+			return fmt.Errorf("inverse suffix array sample %d is invalid", j)
+		}
+	}
+	if fm.isaSamples[0] != fm.primary {
+		return errors.New("text position 0 is not in the sentinel row")
+	}
+	return nil
+}
+
+// validBitVector reports whether the block counts of bv match its words
+func validBitVector(bv *rankBitVector) bool {
+	ones := 0
+	for i, w := range bv.words {
+		if i%blockWords == 0 && bv.blocks[i/blockWords] != ones {
+			return false
+		}
+		ones += bits.OnesCount64(w)
+	}
+	last := len(bv.words) / blockWords
+	return len(bv.words)%blockWords != 0 || bv.blocks[last] == ones
+}
+
+// Close releases the file backing an FM-index loaded by OpenFMIndex. The
+// index must not be used afterwards.
+func (fm *FMIndex) Close() error {
+	return fm.mapping.close()
+}
+
+func openMapping(path string) (*mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return mapFile(f)
+}
+
+// writeFile writes a new file at path, replacing any existing one only once
+// it is complete
+func writeFile(path string, write func(w *bufio.Writer) error) error {
+	f, err := createTemp(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	return commitTemp(f, path, err)
+}
+
+// createTemp creates a file beside path to be renamed over it by commitTemp.
+// Processes mapping the old file keep reading it unchanged, where truncating
+// it in place would crash them.
+func createTemp(path string) (*os.File, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// commitTemp closes a file from createTemp and renames it to path, or
+// removes it when writing it failed with err
+func commitTemp(f *os.File, path string, err error) error {
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// writeUint writes the low width bytes of v in little-endian order. It
+// writes byte by byte, since a slice passed to Write escapes to the heap.
+func writeUint(w *bufio.Writer, v uint64, width int) {
+	for i := 0; i < width; i++ {
+		w.WriteByte(byte(v >> (8 * i)))
+	}
+}
+
+func writeInts(w *bufio.Writer, values []int) {
+	for _, v := range values {
+		writeUint(w, uint64(v), 8)
+	}
+}
+
+func writePadding(w *bufio.Writer, n int) {
+	var zeros [8]byte
+	w.Write(zeros[:(8-n%8)%8])
+}
+
+func writeBitVector(w *bufio.Writer, bv *rankBitVector) {
+	for _, word := range bv.words {
+		writeUint(w, word, 8)
+	}
+	writeInts(w, bv.blocks)
+}
+
+// littleEndian reports whether the host stores integers in the byte order
+// of the file format, allowing slices of uint64 to alias mapped data
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// hostMatchesFormat reports whether slices of int also have the layout of
+// the file format's 64-bit integers
+var hostMatchesFormat = littleEndian && strconv.IntSize == 64
+
+// decoder reads the sections of an index file, remembering the first error
+type decoder struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.data)-d.off {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.data[d.off : d.off+n : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) magic(want string) {
+	b := d.take(len(want) + 4)
+	if d.err != nil {
+		return
+	}
+	if string(b[:len(want)]) != want {
+		d.err = errors.New("not an index file of this type")
+		return
+	}
+	if v := binary.LittleEndian.Uint32(b[len(want):]); v != formatVersion {
+		d.err = fmt.Errorf("unsupported format version %d", v)
+	}
+}
+
+func (d *decoder) uint(width int) int {
+	b := d.take(width)
+	if d.err != nil {
+		return 0
+	}
+	var buf [8]byte
+	copy(buf[:], b)
+	return int(binary.LittleEndian.Uint64(buf[:]))
+}
+
+// length reads a 64-bit count, which cannot exceed the number of bits in
+// the file
+func (d *decoder) length() int {
+	v := d.uint(8)
+	if d.err == nil && (v < 0 || v/8 > len(d.data)) {
+		d.err = errors.New("invalid length in index file")
+	}
+	return v
+}
+
+func (d *decoder) align() {
+	d.take((8 - d.off%8) % 8)
+}
+
+func (d *decoder) string(n int) string {
+	b := d.take(n)
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
+
+// ints reads count 64-bit integers, aliasing the file data when possible
+func (d *decoder) ints(count int) []int {
+	if count < 0 || count > (len(d.data)-d.off)/8 {
+		if d.err == nil {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	b := d.take(count * 8)
+	if d.err != nil {
+		return nil
+	}
+	if count == 0 {
+		return []int{}
+	}
+	if hostMatchesFormat && uintptr(unsafe.Pointer(&b[0]))%8 == 0 {
+		return unsafe.Slice((*int)(unsafe.Pointer(&b[0])), count)
+	}
+
+	values := make([]int, count)
+	for i := range values {
+		values[i] = int(binary.LittleEndian.Uint64(b[i*8:]))
+	}
+	return values
+}
+
+// narrowInts reads count 32-bit integers into a new slice
+func (d *decoder) narrowInts(count int) []int {
+	if count < 0 || count > (len(d.data)-d.off)/4 {
+		if d.err == nil {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	b := d.take(count * 4)
+	if d.err != nil {
+		return nil
+	}
+
+	values := make([]int, count)
+	for i := range values {
+		values[i] = int(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return values
+}
+
+// words reads count 64-bit words, aliasing the file data when possible
+func (d *decoder) words(count int) []uint64 {
+	if count < 0 || count > (len(d.data)-d.off)/8 {
+		if d.err == nil {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	b := d.take(count * 8)
+	if d.err != nil {
+		return nil
+	}
+	if count == 0 {
+		return []uint64{}
+	}
+	if littleEndian && uintptr(unsafe.Pointer(&b[0]))%8 == 0 {
+		return unsafe.Slice((*uint64)(unsafe.Pointer(&b[0])), count)
+	}
+
+	values := make([]uint64, count)
+	for i := range values {
+		values[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	return values
+}
+
+// bitVector reads a rank bit vector of n bits
+func (d *decoder) bitVector(n int) *rankBitVector {
+	bv := &rankBitVector{n: n}
+	bv.words = d.words((n + 63) / 64)
+	bv.blocks = d.ints(len(bv.words)/blockWords + 1)
+	return bv
+}
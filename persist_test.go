@@ -0,0 +1,337 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSuffixArraySaveOpen(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	text := "The quick brown fox jumps over the lazy dog. " + randomString(rng, 1000, "abcd ")
+	sa := NewSuffixArray(text)
+	dir := t.TempDir()
+
+	for name, save := range map[string]func(string) error{
+		"wide":    sa.Save,
+		"compact": sa.SaveCompact,
+	} {
+		path := filepath.Join(dir, name+".sa")
+		if err := save(path); err != nil {
+			t.Fatalf("%s save: %v", name, err)
+		}
+
+		loaded, err := OpenSuffixArray(path)
+		if err != nil {
+			t.Fatalf("%s OpenSuffixArray: %v", name, err)
+		}
+		if loaded.text != sa.text || !reflect.DeepEqual(loaded.suffixes, sa.suffixes) {
+			t.Errorf("%s: loaded suffix array differs from the saved one", name)
+		}
+		if got, want := loaded.Search("fox"), sa.Search("fox"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Search = %v, want %v", name, got, want)
+		}
+		if got, want := loaded.FuzzySearch("quikc", 2), sa.FuzzySearch("quikc", 2); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: FuzzySearch = %v, want %v", name, got, want)
+		}
+		if err := loaded.Close(); err != nil {
+			t.Errorf("%s Close: %v", name, err)
+		}
+	}
+
+	if info, _ := os.Stat(filepath.Join(dir, "compact.sa")); info.Size() >= int64(16+len(text)+8*len(text)) {
+		t.Errorf("compact file is %d bytes, want 32-bit entries", info.Size())
+	}
+}
+
+func TestSuffixArrayStringsOutliveClose(t *testing.T) {
+	text := "the cat sat on the mat, the cat sat"
+	path := filepath.Join(t.TempDir(), "index.sa")
+	if err := NewSuffixArray(text).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	sa, err := OpenSuffixArray(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	longest := sa.LongestRepeatedSubstring()
+	repeats := sa.Repeats(3)
+	all := sa.AllRepeats(3)
+	hits := sa.KWIC("mat", KWICOptions{Context: 4})
+	if err := sa.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading strings that still pointed into the unmapped file crashed
+	var sb strings.Builder
+	sb.WriteString(longest)
+	for _, r := range append(repeats, all...) {
+		sb.WriteString(r.Text)
+	}
+	for _, hit := range hits {
+		sb.WriteString(hit.Before + hit.Match + hit.After)
+	}
+	if longest != "the cat sat" || len(hits) != 1 || hits[0].Before+hits[0].Match+hits[0].After != "the mat, th" {
+		t.Errorf("strings after Close: %q, %+v", longest, hits)
+	}
+}
+
+func TestSaveReplacesOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index.sa")
+	if err := NewSuffixArray("banana").Save(path); err != nil {
+		t.Fatal(err)
+	}
+	sa, err := OpenSuffixArray(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sa.Close()
+
+	// Truncating the mapped file in place made the reader fault
+	if err := NewSuffixArray("a much longer replacement text").Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := sa.Count("ana"); got != 2 {
+		t.Errorf("Count after the file was replaced = %d, want 2", got)
+	}
+	replaced, err := OpenSuffixArray(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced.text != "a much longer replacement text" {
+		t.Errorf("reopened text = %q", replaced.text)
+	}
+	replaced.Close()
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the index", len(entries))
+	}
+}
+
+func TestFMIndexSaveOpen(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	texts := []string{"", "mississippi", "Übermäßig große Straßen " + randomString(rng, 2000, "abcd")}
+
+	for i, text := range texts {
+		fm := NewFMIndex(text, 5)
+		path := filepath.Join(t.TempDir(), "index.fm")
+		if err := fm.Save(path); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+
+		loaded, err := OpenFMIndex(path)
+		if err != nil {
+			t.Fatalf("OpenFMIndex: %v", err)
+		}
+
+		if got := loaded.Extract(0, len(text)); got != text {
+			t.Errorf("text %d: Extract after Open differs", i)
+		}
+		for _, pattern := range []string{"a", "ss", "ß", "abca", "zzz"} {
+			if got, want := loaded.Count(pattern), fm.Count(pattern); got != want {
+				t.Errorf("text %d: Count(%q) = %d, want %d", i, pattern, got, want)
+			}
+			want := fm.Locate(pattern)
+			got := loaded.Locate(pattern)
+			sort.Ints(got)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("text %d: Locate(%q) = %v, want %v", i, pattern, got, want)
+			}
+		}
+		if got, want := loaded.FuzzyLocate("abcd", 1), fm.FuzzyLocate("abcd", 1); !reflect.DeepEqual(got, want) {
+			t.Errorf("text %d: FuzzyLocate after Open differs", i)
+		}
+
+		if err := loaded.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	}
+}
+
+func TestOpenInvalidIndex(t *testing.T) {
+	dir := t.TempDir()
+	sa := NewSuffixArray("banana")
+	saPath := filepath.Join(dir, "index.sa")
+	if err := sa.Save(saPath); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(saPath)
+
+	fmPath := filepath.Join(dir, "index.fm")
+	if err := NewFMIndex("banana", 2).Save(fmPath); err != nil {
+		t.Fatal(err)
+	}
+	fmData, _ := os.ReadFile(fmPath)
+
+	badVersion := append([]byte(nil), data...)
+	badVersion[8] = 9
+
+	files := map[string][]byte{
+		"empty":     {},
+		"truncated": data[:len(data)-3],
+		"version":   badVersion,
+		"fm":        fmData,
+	}
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenSuffixArray(path); err == nil {
+			t.Errorf("OpenSuffixArray(%s) succeeded, want error", name)
+		}
+	}
+
+	if _, err := OpenFMIndex(saPath); err == nil {
+		t.Error("OpenFMIndex of a suffix array file succeeded, want error")
+	}
+	if _, err := OpenFMIndex(filepath.Join(dir, "truncated")); err == nil {
+		t.Error("OpenFMIndex of a truncated file succeeded, want error")
+	}
+	os.WriteFile(filepath.Join(dir, "fm-truncated"), fmData[:len(fmData)-8], 0o644)
+	if _, err := OpenFMIndex(filepath.Join(dir, "fm-truncated")); err == nil {
+		t.Error("OpenFMIndex of a truncated FM-index succeeded, want error")
+	}
+
+	// Well-formed files whose contents are inconsistent. The banana index
+	// has 7 rows, so each bit vector is one word and one block.
+	corrupt := func(offset int, value byte) []byte {
+		out := append([]byte(nil), fmData...)
+		out[offset] = value
+		return out
+	}
+	isaCount := len(fmData) - 8*(1+(7-1)/2+1)
+	fewerSamples := append([]byte(nil), fmData[:len(fmData)-8]...)
+	fewerSamples[isaCount]--
+
+	fmFiles := map[string][]byte{
+		"counts":      corrupt(40, 2),
+		"zeros":       corrupt(2096, 3),
+		"blocks":      corrupt(2168, 1),
+		"isa-samples": fewerSamples,
+	}
+	for name, contents := range fmFiles {
+		path := filepath.Join(dir, "fm-"+name)
+		if err := os.WriteFile(path, contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := OpenFMIndex(path); err == nil {
+			t.Errorf("OpenFMIndex with inconsistent %s succeeded, want error", name)
+		}
+	}
+}
+
+func TestOpenFMIndexInvalidSamples(t *testing.T) {
+	corruptions := map[string]func(fm *FMIndex){
+		// Extract indexed the BWT with these rows and panicked
+		"isa-sample-range": func(fm *FMIndex) {
+			for j := range fm.isaSamples {
+				fm.isaSamples[j] = 1 << 30
+			}
+		},
+		// Locate walked the LF mapping forever looking for a sampled row
+		"no-samples": func(fm *FMIndex) {
+			fm.sampled = newRankBitVector(fm.bwt.n)
+			fm.sampled.index()
+			fm.samplePos = []int{}
+		},
+		"sample-pos-range": func(fm *FMIndex) {
+			fm.samplePos[0] = fm.bwt.n
+		},
+		"isa-sample-unsampled": func(fm *FMIndex) {
+			for row := 0; row < fm.bwt.n; row++ {
+				if !fm.sampled.get(row) {
+					fm.isaSamples[1] = row
+					break
+				}
+			}
+		},
+	}
+
+	for name, corrupt := range corruptions {
+		fm := NewFMIndex("mississippi banana", 3)
+		corrupt(fm)
+		path := filepath.Join(t.TempDir(), "index.fm")
+		if err := fm.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		if loaded, err := OpenFMIndex(path); err == nil {
+			loaded.Close()
+			t.Errorf("OpenFMIndex with %s succeeded, want error", name)
+		}
+	}
+}
+
+func TestSuffixArrayVerify(t *testing.T) {
+	dir := t.TempDir()
+	sa := NewSuffixArray("banana")
+	path := filepath.Join(dir, "index.sa")
+	if err := sa.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+
+	loaded, err := OpenSuffixArray(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Verify(); err != nil {
+		t.Errorf("Verify of a valid file: %v", err)
+	}
+	loaded.Close()
+
+	// Entries follow the 24 byte header and the text padded to 8 bytes
+	entries := 32
+	outOfRange := append([]byte(nil), data...)
+	outOfRange[entries+5*8] = 200
+	swapped := append([]byte(nil), data...)
+	copy(swapped[entries:], data[entries+8:entries+16])
+	copy(swapped[entries+8:], data[entries:entries+8])
+
+	for name, contents := range map[string][]byte{"out-of-range": outOfRange, "swapped": swapped} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, contents, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := OpenSuffixArray(path)
+		if err != nil {
+			t.Fatalf("OpenSuffixArray(%s): %v", name, err)
+		}
+		if err := loaded.Verify(); err == nil {
+			t.Errorf("Verify(%s) succeeded, want error", name)
+		}
+		loaded.Close()
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		if err := NewSuffixArray(randomString(rng, rng.Intn(200), "ab")).Verify(); err != nil {
+			t.Fatalf("Verify of a built suffix array: %v", err)
+		}
+	}
+}
+
+func BenchmarkOpenSuffixArray(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	sa := NewSuffixArray(randomString(rng, 1<<20, "abcdefghijklmnopqrstuvwxyz "))
+	path := filepath.Join(b.TempDir(), "index.sa")
+	if err := sa.Save(path); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		loaded, err := OpenSuffixArray(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		loaded.Close()
+	}
+}
@@ -0,0 +1,1021 @@
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+)
+
+// PhoneticEncoder maps a word to one or more phonetic codes. Words that
+// share any code are considered to sound alike.
+type PhoneticEncoder func(word string) []string
+
+// SingleCode adapts a single-code encoder such as Soundex to a PhoneticEncoder
+func SingleCode(encode func(string) string) PhoneticEncoder {
+	return func(word string) []string {
+		code := encode(word)
+		if code == "" {
+			return nil
+		}
+		return []string{code}
+	}
+}
+
+// upperLetters returns the ASCII letters of s in upper case, dropping
+// everything else
+func upperLetters(s string) []byte {
+	result := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' {
+			result = append(result, c-32)
+		} else if c >= 'A' && c <= 'Z' {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// soundexCodes maps A-Z to American Soundex digits. Vowels and Y map to 0 and
+// separate equal digits, H and W map to '-' and do not.
+var soundexCodes = [26]byte{
+	'0', '1', '2', '3', '0', '1', '2', '-', '0', '2', '2', '4', '5',
+	'5', '0', '1', '2', '6', '2', '3', '0', '1', '-', '2', '0', '2',
+}
+
+// Soundex returns the four character American Soundex code of a word,
+// e.g. "Robert" and "Rupert" both encode to "R163". Non-letters are ignored
+// and an empty string is returned for words without letters.
+func Soundex(word string) string {
+	letters := upperLetters(word)
+	if len(letters) == 0 {
+		return ""
+	}
+
+	code := []byte{letters[0], '0', '0', '0'}
+	n := 1
+	last := soundexCodes[letters[0]-'A']
+
+	for i := 1; i < len(letters) && n < 4; i++ {
+		digit := soundexCodes[letters[i]-'A']
+		switch digit {
+		case '-':
+			// H and W keep the previous digit active
+			continue
+		case '0':
+			last = digit
+			continue
+		}
+		if digit != last {
+			code[n] = digit
+			n++
+		}
+		last = digit
+	}
+
+	return string(code)
+}
+
+// Metaphone returns the original Metaphone code of a word as described by
+// Lawrence Philips. The code is not truncated, and "0" stands for "th".
+func Metaphone(word string) string {
+	letters := upperLetters(word)
+	if len(letters) == 0 {
+		return ""
+	}
+	if len(letters) == 1 {
+		return string(letters)
+	}
+
+	// Initial letter exceptions
+	switch letters[0] {
+	case 'K', 'G', 'P':
+		if letters[1] == 'N' {
+			letters = letters[1:]
+		}
+	case 'A':
+		if letters[1] == 'E' {
+			letters = letters[1:]
+		}
+	case 'W':
+		if letters[1] == 'R' {
+			letters = letters[1:]
+		} else if letters[1] == 'H' {
+			letters = letters[1:]
+			letters[0] = 'W'
+		}
+	case 'X':
+		letters[0] = 'S'
+	}
+
+	n := len(letters)
+	at := func(i int) byte {
+		if i < 0 || i >= n {
+			return 0
+		}
+		return letters[i]
+	}
+	isVowel := func(i int) bool {
+		return at(i) != 0 && strings.IndexByte("AEIOU", at(i)) >= 0
+	}
+	isFrontVowel := func(i int) bool {
+		return at(i) != 0 && strings.IndexByte("EIY", at(i)) >= 0
+	}
+	regionMatch := func(i int, s string) bool {
+		return i+len(s) <= n && string(letters[i:i+len(s)]) == s
+	}
+
+	code := make([]byte, 0, n)
+	for i := 0; i < n; i++ {
+		c := letters[i]
+
+		// Skip doubled letters except C
+		if c != 'C' && i > 0 && letters[i-1] == c {
+			continue
+		}
+
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			if i == 0 {
+				code = append(code, c)
+			}
+		case 'B':
+			// Silent in a trailing MB
+			if !(i == n-1 && at(i-1) == 'M') {
+				code = append(code, 'B')
+			}
+		case 'C':
+			switch {
+			case at(i-1) == 'S' && isFrontVowel(i+1):
+				// SCI, SCE and SCY are silent
+			case regionMatch(i, "CIA"):
+				code = append(code, 'X')
+			case isFrontVowel(i + 1):
+				code = append(code, 'S')
+			case at(i-1) == 'S' && at(i+1) == 'H':
+				code = append(code, 'K')
+			case at(i+1) == 'H':
+				if i == 0 && n >= 3 && isVowel(2) {
+					code = append(code, 'K')
+				} else {
+					code = append(code, 'X')
+				}
+			default:
+				code = append(code, 'K')
+			}
+		case 'D':
+			if at(i+1) == 'G' && isFrontVowel(i+2) {
+				code = append(code, 'J')
+				i += 2
+			} else {
+				code = append(code, 'T')
+			}
+		case 'G':
+			switch {
+			case at(i+1) == 'H' && !isVowel(i+2):
+				// Silent in GH unless followed by a vowel
+			case i > 0 && (regionMatch(i, "GN") || regionMatch(i, "GNED")):
+			case isFrontVowel(i+1) && at(i-1) != 'G':
+				code = append(code, 'J')
+			default:
+				code = append(code, 'K')
+			}
+		case 'H':
+			// Silent at the end and after C, S, P, T and G
+			if i < n-1 && (i == 0 || strings.IndexByte("CSPTG", at(i-1)) < 0) && isVowel(i+1) {
+				code = append(code, 'H')
+			}
+		case 'K':
+			if at(i-1) != 'C' {
+				code = append(code, 'K')
+			}
+		case 'P':
+			if at(i+1) == 'H' {
+				code = append(code, 'F')
+			} else {
+				code = append(code, 'P')
+			}
+		case 'Q':
+			code = append(code, 'K')
+		case 'S':
+			if regionMatch(i, "SH") || regionMatch(i, "SIO") || regionMatch(i, "SIA") {
+				code = append(code, 'X')
+			} else {
+				code = append(code, 'S')
+			}
+		case 'T':
+			switch {
+			case regionMatch(i, "TIA") || regionMatch(i, "TIO"):
+				code = append(code, 'X')
+			case regionMatch(i, "TCH"):
+			case regionMatch(i, "TH"):
+				code = append(code, '0')
+			default:
+				code = append(code, 'T')
+			}
+		case 'V':
+			code = append(code, 'F')
+		case 'W', 'Y':
+			if isVowel(i + 1) {
+				code = append(code, c)
+			}
+		case 'X':
+			code = append(code, 'K', 'S')
+		case 'Z':
+			code = append(code, 'S')
+		default:
+			// F, J, L, M, N and R encode as themselves
+			code = append(code, c)
+		}
+	}
+
+	return string(code)
+}
+
+// doubleMetaphoneMaxLen is the code length used by the reference
+// Double Metaphone implementation
+const doubleMetaphoneMaxLen = 4
+
+// doubleMetaphone holds the state of a single Double Metaphone encoding
+type doubleMetaphone struct {
+	word          []rune
+	primary       []rune
+	alternate     []rune
+	slavoGermanic bool
+}
+
+// DoubleMetaphone returns the primary and alternate Double Metaphone codes of
+// a word. The alternate code equals the primary one when the word has a
+// single likely pronunciation. For example "Smith" encodes to "SM0" and
+// "XMT", sharing its alternate code with the primary code of "Schmidt".
+func DoubleMetaphone(word string) (primary, alternate string) {
+	word = strings.ToUpper(strings.TrimSpace(word))
+	if word == "" {
+		return "", ""
+	}
+
+	dm := &doubleMetaphone{word: []rune(word)}
+	dm.slavoGermanic = strings.Contains(word, "W") || strings.Contains(word, "K") ||
+		strings.Contains(word, "CZ") || strings.Contains(word, "WITZ")
+	dm.encode()
+
+	return string(dm.primary), string(dm.alternate)
+}
+
+// DoubleMetaphoneCodes is a PhoneticEncoder returning the distinct Double
+// Metaphone codes of a word
+func DoubleMetaphoneCodes(word string) []string {
+	primary, alternate := DoubleMetaphone(word)
+	if primary == "" {
+		return nil
+	}
+	if alternate == primary {
+		return []string{primary}
+	}
+	return []string{primary, alternate}
+}
+
+func (dm *doubleMetaphone) at(i int) rune {
+	if i < 0 || i >= len(dm.word) {
+		return 0
+	}
+	return dm.word[i]
+}
+
+// matches reports whether the word holds any of the given strings at i
+func (dm *doubleMetaphone) matches(i int, candidates ...string) bool {
+	if i < 0 {
+		return false
+	}
+	for _, c := range candidates {
+		n := len(c)
+		if i+n <= len(dm.word) && string(dm.word[i:i+n]) == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (dm *doubleMetaphone) isVowel(i int) bool {
+	switch dm.at(i) {
+	case 'A', 'E', 'I', 'O', 'U', 'Y':
+		return true
+	}
+	return false
+}
+
+func (dm *doubleMetaphone) complete() bool {
+	return len(dm.primary) >= doubleMetaphoneMaxLen && len(dm.alternate) >= doubleMetaphoneMaxLen
+}
+
+func (dm *doubleMetaphone) appendPrimary(s string) {
+	for _, r := range s {
+		if len(dm.primary) < doubleMetaphoneMaxLen {
+			dm.primary = append(dm.primary, r)
+		}
+	}
+}
+
+func (dm *doubleMetaphone) appendAlternate(s string) {
+	for _, r := range s {
+		if len(dm.alternate) < doubleMetaphoneMaxLen {
+			dm.alternate = append(dm.alternate, r)
+		}
+	}
+}
+
+// add appends to both codes, or to each separately when an alternate is given
+func (dm *doubleMetaphone) add(primary string, alternate ...string) {
+	dm.appendPrimary(primary)
+	if len(alternate) > 0 {
+		dm.appendAlternate(alternate[0])
+	} else {
+		dm.appendAlternate(primary)
+	}
+}
+
+// skipDouble advances past c, and past a following copy of it
+func (dm *doubleMetaphone) skipDouble(i int, c rune) int {
+	if dm.at(i+1) == c {
+		return i + 2
+	}
+	return i + 1
+}
+
+func (dm *doubleMetaphone) germanicStart() bool {
+	return dm.matches(0, "VAN ", "VON ") || dm.matches(0, "SCH")
+}
+
+func (dm *doubleMetaphone) encode() {
+	i := 0
+	if dm.matches(0, "GN", "KN", "PN", "WR", "PS") {
+		i = 1
+	}
+
+	last := len(dm.word) - 1
+	for !dm.complete() && i <= last {
+		switch dm.at(i) {
+		case 'A', 'E', 'I', 'O', 'U', 'Y':
+			if i == 0 {
+				dm.add("A")
+			}
+			i++
+		case 'B':
+			dm.add("P")
+			i = dm.skipDouble(i, 'B')
+		case 'Ç':
+			dm.add("S")
+			i++
+		case 'C':
+			i = dm.handleC(i)
+		case 'D':
+			i = dm.handleD(i)
+		case 'F':
+			dm.add("F")
+			i = dm.skipDouble(i, 'F')
+		case 'G':
+			i = dm.handleG(i)
+		case 'H':
+			i = dm.handleH(i)
+		case 'J':
+			i = dm.handleJ(i)
+		case 'K':
+			dm.add("K")
+			i = dm.skipDouble(i, 'K')
+		case 'L':
+			i = dm.handleL(i)
+		case 'M':
+			dm.add("M")
+			if dm.at(i+1) == 'M' || dm.matches(i-1, "UMB") && (i+1 == last || dm.matches(i+2, "ER")) {
+				i += 2
+			} else {
+				i++
+			}
+		case 'N':
+			dm.add("N")
+			i = dm.skipDouble(i, 'N')
+		case 'Ñ':
+			dm.add("N")
+			i++
+		case 'P':
+			if dm.at(i+1) == 'H' {
+				dm.add("F")
+				i += 2
+			} else {
+				dm.add("P")
+				if dm.matches(i+1, "P", "B") {
+					i += 2
+				} else {
+					i++
+				}
+			}
+		case 'Q':
+			dm.add("K")
+			i = dm.skipDouble(i, 'Q')
+		case 'R':
+			if i == last && !dm.slavoGermanic && dm.matches(i-2, "IE") && !dm.matches(i-4, "ME", "MA") {
+				dm.appendAlternate("R")
+			} else {
+				dm.add("R")
+			}
+			i = dm.skipDouble(i, 'R')
+		case 'S':
+			i = dm.handleS(i)
+		case 'T':
+			i = dm.handleT(i)
+		case 'V':
+			dm.add("F")
+			i = dm.skipDouble(i, 'V')
+		case 'W':
+			i = dm.handleW(i)
+		case 'X':
+			if i == 0 {
+				dm.add("S")
+				i++
+				break
+			}
+			// Silent in French endings such as -IAUX and -EAUX
+			if !(i == last && (dm.matches(i-3, "IAU", "EAU") || dm.matches(i-2, "AU", "OU"))) {
+				dm.add("KS")
+			}
+			if dm.matches(i+1, "C", "X") {
+				i += 2
+			} else {
+				i++
+			}
+		case 'Z':
+			if dm.at(i+1) == 'H' {
+				dm.add("J")
+				i += 2
+				break
+			}
+			if dm.matches(i+1, "ZO", "ZI", "ZA") || dm.slavoGermanic && i > 0 && dm.at(i-1) != 'T' {
+				dm.add("S", "TS")
+			} else {
+				dm.add("S")
+			}
+			i = dm.skipDouble(i, 'Z')
+		default:
+			i++
+		}
+	}
+}
+
+func (dm *doubleMetaphone) handleC(i int) int {
+	switch {
+	case dm.germanicCH(i):
+		// Various Germanic cases such as BACHER and MACHER
+		dm.add("K")
+		return i + 2
+	case i == 0 && dm.matches(i, "CAESAR"):
+		dm.add("S")
+		return i + 2
+	case dm.matches(i, "CH"):
+		return dm.handleCH(i)
+	case dm.matches(i, "CZ") && !dm.matches(i-2, "WICZ"):
+		dm.add("S", "X")
+		return i + 2
+	case dm.matches(i+1, "CIA"):
+		dm.add("X")
+		return i + 3
+	case dm.matches(i, "CC") && !(i == 1 && dm.at(0) == 'M'):
+		if dm.matches(i+2, "I", "E", "H") && !dm.matches(i+2, "HU") {
+			if i == 1 && dm.at(0) == 'A' || dm.matches(i-1, "UCCEE", "UCCES") {
+				dm.add("KS")
+			} else {
+				dm.add("X")
+			}
+			return i + 3
+		}
+		dm.add("K")
+		return i + 2
+	case dm.matches(i, "CK", "CG", "CQ"):
+		dm.add("K")
+		return i + 2
+	case dm.matches(i, "CI", "CE", "CY"):
+		if dm.matches(i, "CIO", "CIE", "CIA") {
+			dm.add("S", "X")
+		} else {
+			dm.add("S")
+		}
+		return i + 2
+	}
+
+	dm.add("K")
+	if dm.matches(i+1, " C", " Q", " G") {
+		return i + 3
+	}
+	if dm.matches(i+1, "C", "K", "Q") && !dm.matches(i+1, "CE", "CI") {
+		return i + 2
+	}
+	return i + 1
+}
+
+func (dm *doubleMetaphone) germanicCH(i int) bool {
+	if dm.matches(i, "CHIA") {
+		return true
+	}
+	if i <= 1 || dm.isVowel(i-2) || !dm.matches(i-1, "ACH") {
+		return false
+	}
+	c := dm.at(i + 2)
+	return c != 'I' && c != 'E' || dm.matches(i-2, "BACHER", "MACHER")
+}
+
+func (dm *doubleMetaphone) handleCH(i int) int {
+	if i > 0 && dm.matches(i, "CHAE") {
+		dm.add("K", "X")
+		return i + 2
+	}
+
+	// Greek roots such as CHEMISTRY and CHORUS
+	greek := i == 0 &&
+		(dm.matches(i+1, "HARAC", "HARIS") || dm.matches(i+1, "HOR", "HYM", "HIA", "HEM")) &&
+		!dm.matches(0, "CHORE")
+	if greek {
+		dm.add("K")
+		return i + 2
+	}
+
+	hard := dm.germanicStart() ||
+		dm.matches(i-2, "ORCHES", "ARCHIT", "ORCHID") ||
+		dm.matches(i+2, "T", "S") ||
+		(dm.matches(i-1, "A", "O", "U", "E") || i == 0) &&
+			(dm.matches(i+2, "L", "R", "N", "M", "B", "H", "F", "V", "W", " ") || i+1 == len(dm.word)-1)
+	if hard {
+		dm.add("K")
+		return i + 2
+	}
+
+	if i > 0 {
+		if dm.matches(0, "MC") {
+			dm.add("K")
+		} else {
+			dm.add("X", "K")
+		}
+	} else {
+		dm.add("X")
+	}
+	return i + 2
+}
+
+func (dm *doubleMetaphone) handleD(i int) int {
+	if dm.matches(i, "DG") {
+		if dm.matches(i+2, "I", "E", "Y") {
+			dm.add("J")
+			return i + 3
+		}
+		dm.add("TK")
+		return i + 2
+	}
+	if dm.matches(i, "DT", "DD") {
+		dm.add("T")
+		return i + 2
+	}
+	dm.add("T")
+	return i + 1
+}
+
+func (dm *doubleMetaphone) handleG(i int) int {
+	next := dm.at(i + 1)
+	switch {
+	case next == 'H':
+		return dm.handleGH(i)
+	case next == 'N':
+		if i == 1 && dm.isVowel(0) && !dm.slavoGermanic {
+			dm.add("KN", "N")
+		} else if !dm.matches(i+2, "EY") && !dm.slavoGermanic {
+			dm.add("N", "KN")
+		} else {
+			dm.add("KN")
+		}
+		return i + 2
+	case dm.matches(i+1, "LI") && !dm.slavoGermanic:
+		dm.add("KL", "L")
+		return i + 2
+	case i == 0 && (next == 'Y' || dm.matches(i+1, "ES", "EP", "EB", "EL", "EY", "IB", "IL", "IN", "IE", "EI", "ER")):
+		dm.add("K", "J")
+		return i + 2
+	case (dm.matches(i+1, "ER") || next == 'Y') &&
+		!dm.matches(0, "DANGER", "RANGER", "MANGER") &&
+		!dm.matches(i-1, "E", "I") &&
+		!dm.matches(i-1, "RGY", "OGY"):
+		dm.add("K", "J")
+		return i + 2
+	case dm.matches(i+1, "E", "I", "Y") || dm.matches(i-1, "AGGI", "OGGI"):
+		if dm.germanicStart() || dm.matches(i+1, "ET") {
+			dm.add("K")
+		} else if dm.matches(i+1, "IER") {
+			dm.add("J")
+		} else {
+			dm.add("J", "K")
+		}
+		return i + 2
+	case next == 'G':
+		dm.add("K")
+		return i + 2
+	}
+	dm.add("K")
+	return i + 1
+}
+
+func (dm *doubleMetaphone) handleGH(i int) int {
+	if i > 0 && !dm.isVowel(i-1) {
+		dm.add("K")
+		return i + 2
+	}
+	if i == 0 {
+		if dm.at(i+2) == 'I' {
+			dm.add("J")
+		} else {
+			dm.add("K")
+		}
+		return i + 2
+	}
+	// Silent in words such as HUGH, BOUGH and BROUGHTON
+	if dm.matches(i-2, "B", "H", "D") || dm.matches(i-3, "B", "H", "D") || dm.matches(i-4, "B", "H") {
+		return i + 2
+	}
+	// LAUGH, McLAUGHLIN, COUGH, GOUGH, ROUGH, TOUGH
+	if i > 2 && dm.at(i-1) == 'U' && dm.matches(i-3, "C", "G", "L", "R", "T") {
+		dm.add("F")
+	} else if dm.at(i-1) != 'I' {
+		dm.add("K")
+	}
+	return i + 2
+}
+
+func (dm *doubleMetaphone) handleH(i int) int {
+	// Only keep H between vowels or at the start before a vowel
+	if (i == 0 || dm.isVowel(i-1)) && dm.isVowel(i+1) {
+		dm.add("H")
+		return i + 2
+	}
+	return i + 1
+}
+
+func (dm *doubleMetaphone) handleJ(i int) int {
+	if dm.matches(i, "JOSE") || dm.matches(0, "SAN ") {
+		if i == 0 && dm.at(i+4) == ' ' || len(dm.word) == 4 || dm.matches(0, "SAN ") {
+			dm.add("H")
+		} else {
+			dm.add("J", "H")
+		}
+		return i + 1
+	}
+
+	if i == 0 {
+		dm.add("J", "A")
+	} else if dm.isVowel(i-1) && !dm.slavoGermanic && (dm.at(i+1) == 'A' || dm.at(i+1) == 'O') {
+		dm.add("J", "H")
+	} else if i == len(dm.word)-1 {
+		dm.appendPrimary("J")
+	} else if !dm.matches(i+1, "L", "T", "K", "S", "N", "M", "B", "Z") && !dm.matches(i-1, "S", "K", "L") {
+		dm.add("J")
+	}
+	return dm.skipDouble(i, 'J')
+}
+
+func (dm *doubleMetaphone) handleL(i int) int {
+	if dm.at(i+1) != 'L' {
+		dm.add("L")
+		return i + 1
+	}
+
+	// Spanish endings such as CABRILLO and GALLEGOS
+	n := len(dm.word)
+	spanish := i == n-3 && dm.matches(i-1, "ILLO", "ILLA", "ALLE") ||
+		(dm.matches(n-2, "AS", "OS") || dm.matches(n-1, "A", "O")) && dm.matches(i-1, "ALLE")
+	if spanish {
+		dm.appendPrimary("L")
+	} else {
+		dm.add("L")
+	}
+	return i + 2
+}
+
+func (dm *doubleMetaphone) handleS(i int) int {
+	switch {
+	case dm.matches(i-1, "ISL", "YSL"):
+		// Silent in ISLAND, CARLISLE and CARLYSLE
+		return i + 1
+	case i == 0 && dm.matches(i, "SUGAR"):
+		dm.add("X", "S")
+		return i + 1
+	case dm.matches(i, "SH"):
+		if dm.matches(i+1, "HEIM", "HOEK", "HOLM", "HOLZ") {
+			dm.add("S")
+		} else {
+			dm.add("X")
+		}
+		return i + 2
+	case dm.matches(i, "SIO", "SIA") || dm.matches(i, "SIAN"):
+		if dm.slavoGermanic {
+			dm.add("S")
+		} else {
+			dm.add("S", "X")
+		}
+		return i + 3
+	case i == 0 && dm.matches(i+1, "M", "N", "L", "W") || dm.matches(i+1, "Z"):
+		dm.add("S", "X")
+		if dm.matches(i+1, "Z") {
+			return i + 2
+		}
+		return i + 1
+	case dm.matches(i, "SC"):
+		return dm.handleSC(i)
+	}
+
+	// French endings such as RESNAIS and ARTOIS
+	if i == len(dm.word)-1 && dm.matches(i-2, "AI", "OI") {
+		dm.appendAlternate("S")
+	} else {
+		dm.add("S")
+	}
+	if dm.matches(i+1, "S", "Z") {
+		return i + 2
+	}
+	return i + 1
+}
+
+func (dm *doubleMetaphone) handleSC(i int) int {
+	if dm.at(i+2) == 'H' {
+		if dm.matches(i+3, "OO", "ER", "EN", "UY", "ED", "EM") {
+			// Dutch origin such as SCHOOL and SCHOONER
+			if dm.matches(i+3, "ER", "EN") {
+				dm.add("X", "SK")
+			} else {
+				dm.add("SK")
+			}
+		} else if i == 0 && !dm.isVowel(3) && dm.at(3) != 'W' {
+			dm.add("X", "S")
+		} else {
+			dm.add("X")
+		}
+	} else if dm.matches(i+2, "I", "E", "Y") {
+		dm.add("S")
+	} else {
+		dm.add("SK")
+	}
+	return i + 3
+}
+
+func (dm *doubleMetaphone) handleT(i int) int {
+	switch {
+	case dm.matches(i, "TION"):
+		dm.add("X")
+		return i + 3
+	case dm.matches(i, "TIA", "TCH"):
+		dm.add("X")
+		return i + 3
+	case dm.matches(i, "TH") || dm.matches(i, "TTH"):
+		if dm.matches(i+2, "OM", "AM") || dm.germanicStart() {
+			dm.add("T")
+		} else {
+			dm.add("0", "T")
+		}
+		return i + 2
+	}
+	dm.add("T")
+	if dm.matches(i+1, "T", "D") {
+		return i + 2
+	}
+	return i + 1
+}
+
+func (dm *doubleMetaphone) handleW(i int) int {
+	if dm.matches(i, "WR") {
+		dm.add("R")
+		return i + 2
+	}
+
+	if i == 0 && (dm.isVowel(i+1) || dm.matches(i, "WH")) {
+		if dm.isVowel(i + 1) {
+			dm.add("A", "F")
+		} else {
+			dm.add("A")
+		}
+		return i + 1
+	}
+
+	// Polish names such as FILIPOWICZ, and Germanic W pronounced as V
+	if i == len(dm.word)-1 && dm.isVowel(i-1) ||
+		dm.matches(i-1, "EWSKI", "EWSKY", "OWSKI", "OWSKY") ||
+		dm.matches(0, "SCH") {
+		dm.appendAlternate("F")
+		return i + 1
+	}
+	if dm.matches(i, "WICZ", "WITZ") {
+		dm.add("TS", "FX")
+		return i + 4
+	}
+	return i + 1
+}
+
+// NYSIIS returns the New York State Identification and Intelligence System
+// code of a word, truncated to the customary six characters
+func NYSIIS(word string) string {
+	letters := upperLetters(word)
+	if len(letters) == 0 {
+		return ""
+	}
+
+	s := string(letters)
+	switch {
+	case strings.HasPrefix(s, "MAC"):
+		s = "MCC" + s[3:]
+	case strings.HasPrefix(s, "KN"):
+		s = "NN" + s[2:]
+	case strings.HasPrefix(s, "K"):
+		s = "C" + s[1:]
+	case strings.HasPrefix(s, "PH"), strings.HasPrefix(s, "PF"):
+		s = "FF" + s[2:]
+	case strings.HasPrefix(s, "SCH"):
+		s = "SSS" + s[3:]
+	}
+
+	for _, suffix := range []string{"EE", "IE"} {
+		if strings.HasSuffix(s, suffix) {
+			s = s[:len(s)-2] + "Y"
+		}
+	}
+	for _, suffix := range []string{"DT", "RT", "RD", "NT", "ND"} {
+		if strings.HasSuffix(s, suffix) {
+			s = s[:len(s)-2] + "D"
+		}
+	}
+
+	isVowel := func(c byte) bool {
+		return c == 'A' || c == 'E' || c == 'I' || c == 'O' || c == 'U'
+	}
+
+	chars := []byte(s)
+	n := len(chars)
+	key := []byte{chars[0]}
+
+	for i := 1; i < n; i++ {
+		var next, afterNext byte = ' ', ' '
+		if i < n-1 {
+			next = chars[i+1]
+		}
+		if i < n-2 {
+			afterNext = chars[i+2]
+		}
+
+		var replacement string
+		curr := chars[i]
+		switch {
+		case curr == 'E' && next == 'V':
+			replacement = "AF"
+		case isVowel(curr):
+			replacement = "A"
+		case curr == 'Q':
+			replacement = "G"
+		case curr == 'Z':
+			replacement = "S"
+		case curr == 'M':
+			replacement = "N"
+		case curr == 'K' && next == 'N':
+			replacement = "NN"
+		case curr == 'K':
+			replacement = "C"
+		case curr == 'S' && next == 'C' && afterNext == 'H':
+			replacement = "SSS"
+		case curr == 'P' && next == 'H':
+			replacement = "FF"
+		case curr == 'H' && (!isVowel(chars[i-1]) || !isVowel(next)):
+			replacement = string(chars[i-1])
+		case curr == 'W' && isVowel(chars[i-1]):
+			replacement = string(chars[i-1])
+		default:
+			replacement = string(curr)
+		}
+
+		// Replacements overwrite the following characters in place
+		copy(chars[i:], replacement)
+		if chars[i] != chars[i-1] {
+			key = append(key, chars[i])
+		}
+	}
+
+	if len(key) > 1 {
+		if key[len(key)-1] == 'S' {
+			key = key[:len(key)-1]
+		}
+		if len(key) > 2 && key[len(key)-2] == 'A' && key[len(key)-1] == 'Y' {
+			key = append(key[:len(key)-2], 'Y')
+		}
+		if len(key) > 1 && key[len(key)-1] == 'A' {
+			key = key[:len(key)-1]
+		}
+	}
+
+	if len(key) > 6 {
+		key = key[:6]
+	}
+	return string(key)
+}
+
+// PhoneticIndex buckets words by phonetic code and ranks the words that sound
+// like a query with a distance function, so sound-alike matches can be
+// combined with BKTree typo matches
+type PhoneticIndex struct {
+	encode   PhoneticEncoder
+	distance DistanceFunc
+	buckets  map[string][]int
+	words    []string
+	seen     map[string]struct{}
+}
+
+// NewPhoneticIndex creates a phonetic index ranking candidates by
+// Levenshtein distance
+func NewPhoneticIndex(encode PhoneticEncoder) *PhoneticIndex {
+	return NewPhoneticIndexWithDistance(encode, LevenshteinDistance)
+}
+
+// NewPhoneticIndexWithDistance creates a phonetic index ranking candidates
+// with a custom distance function
+func NewPhoneticIndexWithDistance(encode PhoneticEncoder, distFunc DistanceFunc) *PhoneticIndex {
+	return &PhoneticIndex{
+		encode:   encode,
+		distance: distFunc,
+		buckets:  make(map[string][]int),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// Add inserts a word into every bucket of its phonetic codes
+func (pi *PhoneticIndex) Add(word string) {
+	if _, exists := pi.seen[word]; exists {
+		return
+	}
+	pi.seen[word] = struct{}{}
+
+	id := len(pi.words)
+	pi.words = append(pi.words, word)
+
+	for _, code := range pi.encode(word) {
+		pi.buckets[code] = append(pi.buckets[code], id)
+	}
+}
+
+// Candidates returns the words sharing at least one phonetic code with the
+// query, in insertion order
+func (pi *PhoneticIndex) Candidates(query string) []string {
+	ids := pi.candidateIDs(query)
+	results := make([]string, len(ids))
+	for i, id := range ids {
+		results[i] = pi.words[id]
+	}
+	return results
+}
+
+func (pi *PhoneticIndex) candidateIDs(query string) []int {
+	codes := pi.encode(query)
+	if len(codes) == 1 {
+		return pi.buckets[codes[0]]
+	}
+
+	seen := make(map[int]struct{})
+	var ids []int
+	for _, code := range codes {
+		for _, id := range pi.buckets[code] {
+			if _, exists := seen[id]; !exists {
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Search returns the words that sound like the query and are within
+// maxDistance of it, ordered by distance and then alphabetically
+func (pi *PhoneticIndex) Search(query string, maxDistance int) []SearchResult {
+	var results []SearchResult
+	for _, id := range pi.candidateIDs(query) {
+		word := pi.words[id]
+		dist := pi.distance(query, word)
+		if dist <= maxDistance {
+			results = append(results, SearchResult{
+				Word:     word,
+				Distance: dist,
+				Score:    normalizeDistance(dist, MaxLengthBound(query, word)),
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Distance != results[j].Distance {
+			return results[i].Distance < results[j].Distance
+		}
+		return results[i].Word < results[j].Word
+	})
+
+	return results
+}
+
+// Size returns the number of words in the index
+func (pi *PhoneticIndex) Size() int {
+	return len(pi.words)
+}
@@ -0,0 +1,156 @@
+package fuzzy
+
+import (
+	"testing"
+)
+
+func TestSoundex(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"", ""},
+		{"123", ""},
+		{"Robert", "R163"},
+		{"Rupert", "R163"},
+		{"Rubin", "R150"},
+		{"Ashcraft", "A261"},
+		{"Tymczak", "T522"},
+		{"Pfister", "P236"},
+		{"Smith", "S530"},
+		{"Smyth", "S530"},
+		{"Lee", "L000"},
+	}
+
+	for _, tt := range tests {
+		got := Soundex(tt.word)
+		if got != tt.want {
+			t.Errorf("Soundex(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestMetaphone(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"", ""},
+		{"Smith", "SM0"},
+		{"Smyth", "SM0"},
+		{"Knight", "NT"},
+		{"Phone", "FN"},
+		{"Xavier", "SFR"},
+		{"Science", "SNS"},
+		{"Dumb", "TM"},
+		{"Judge", "JJ"},
+	}
+
+	for _, tt := range tests {
+		got := Metaphone(tt.word)
+		if got != tt.want {
+			t.Errorf("Metaphone(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestDoubleMetaphone(t *testing.T) {
+	tests := []struct {
+		word               string
+		primary, alternate string
+	}{
+		{"", "", ""},
+		{"Smith", "SM0", "XMT"},
+		{"Schmidt", "XMT", "SMT"},
+		{"Catherine", "K0RN", "KTRN"},
+		{"Kathryn", "K0RN", "KTRN"},
+		{"Thompson", "TMPS", "TMPS"},
+		{"Jose", "HS", "HS"},
+		{"Xavier", "SF", "SFR"},
+		{"Caesar", "SSR", "SSR"},
+		{"Gnome", "NM", "NM"},
+	}
+
+	for _, tt := range tests {
+		primary, alternate := DoubleMetaphone(tt.word)
+		if primary != tt.primary || alternate != tt.alternate {
+			t.Errorf("DoubleMetaphone(%q) = (%q, %q), want (%q, %q)",
+				tt.word, primary, alternate, tt.primary, tt.alternate)
+		}
+	}
+}
+
+func TestNYSIIS(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"", ""},
+		{"Knuth", "NAT"},
+		{"Koehn", "CAN"},
+		{"Mackie", "MCY"},
+		{"McKee", "MCY"},
+		{"Bart", "BAD"},
+		{"Hurd", "HAD"},
+		{"Pfeister", "FASTAR"},
+		{"Westerlund", "WASTAR"},
+	}
+
+	for _, tt := range tests {
+		got := NYSIIS(tt.word)
+		if got != tt.want {
+			t.Errorf("NYSIIS(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneticIndex(t *testing.T) {
+	index := NewPhoneticIndex(DoubleMetaphoneCodes)
+	names := []string{"Smith", "Smyth", "Schmidt", "Catherine", "Kathryn", "Johnson", "Smith"}
+	for _, name := range names {
+		index.Add(name)
+	}
+
+	if index.Size() != 6 {
+		t.Errorf("Size() = %d, want 6", index.Size())
+	}
+
+	results := index.Search("Smith", 10)
+	if len(results) != 3 {
+		t.Fatalf("Search(%q) returned %v, want 3 results", "Smith", results)
+	}
+	if results[0].Word != "Smith" || results[0].Distance != 0 {
+		t.Errorf("Search(%q) first result = %v, want exact match", "Smith", results[0])
+	}
+	if results[1].Word != "Smyth" {
+		t.Errorf("Search(%q) second result = %v, want Smyth", "Smith", results[1])
+	}
+
+	results = index.Search("Katherine", 3)
+	if len(results) != 2 {
+		t.Errorf("Search(%q) returned %v, want Catherine and Kathryn", "Katherine", results)
+	}
+
+	if got := index.Candidates("Zzyzx"); len(got) != 0 {
+		t.Errorf("Candidates(%q) = %v, want none", "Zzyzx", got)
+	}
+}
+
+func TestPhoneticIndexSoundex(t *testing.T) {
+	index := NewPhoneticIndex(SingleCode(Soundex))
+	for _, name := range []string{"Robert", "Rupert", "Rubin"} {
+		index.Add(name)
+	}
+
+	got := index.Candidates("Robbert")
+	if len(got) != 2 || got[0] != "Robert" || got[1] != "Rupert" {
+		t.Errorf("Candidates(%q) = %v, want [Robert Rupert]", "Robbert", got)
+	}
+}
+
+func BenchmarkDoubleMetaphone(b *testing.B) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DoubleMetaphone("Catherine")
+	}
+}
@@ -0,0 +1,105 @@
+package fuzzy
+
+// CompiledQuery precomputes the pattern tables of a query so it can be
+// scored against many candidates without re-processing the query or
+// allocating per call. Queries of up to 64 bytes use Myers' bit-parallel
+// algorithm as refined by Hyyrö; longer ones reuse internal rows, so a
+// CompiledQuery must not be shared between goroutines.
+type CompiledQuery struct {
+	query string
+	peq   [256]uint64 // Bit i of peq[c] is set when query[i] == c
+	prev  []int
+	curr  []int
+}
+
+// CompileQuery prepares query for repeated distance computations
+func CompileQuery(query string) *CompiledQuery {
+	q := &CompiledQuery{query: query}
+
+	if len(query) <= 64 {
+		for i := 0; i < len(query); i++ {
+			q.peq[query[i]] |= 1 << i
+		}
+	} else {
+		q.prev = make([]int, len(query)+1)
+		q.curr = make([]int, len(query)+1)
+	}
+
+	return q
+}
+
+// Distance returns LevenshteinDistance(query, candidate)
+func (q *CompiledQuery) Distance(candidate string) int {
+	m := len(q.query)
+	if m == 0 {
+		return len(candidate)
+	}
+	if len(candidate) == 0 {
+		return m
+	}
+	if m > 64 {
+		return q.rowDistance(candidate)
+	}
+
+	// Pv and Mv hold the positive and negative vertical deltas of the
+	// current column of the edit distance matrix
+	pv := ^uint64(0)
+	mv := uint64(0)
+	last := uint64(1) << (m - 1)
+	score := m
+
+	for i := 0; i < len(candidate); i++ {
+		eq := q.peq[candidate[i]]
+		xv := eq | mv
+		xh := (((eq & pv) + pv) ^ pv) | eq
+		ph := mv | ^(xh | pv)
+		mh := pv & xh
+
+		if ph&last != 0 {
+			score++
+		} else if mh&last != 0 {
+			score--
+		}
+
+		// The first row grows by one per column, so shift in a positive delta
+		ph = ph<<1 | 1
+		mh <<= 1
+		pv = mh | ^(xv | ph)
+		mv = ph & xv
+	}
+
+	return score
+}
+
+// rowDistance is the two-row dynamic programming fallback for long queries
+func (q *CompiledQuery) rowDistance(candidate string) int {
+	prev, curr := q.prev, q.curr
+	for i := range prev {
+		prev[i] = i
+	}
+
+	for j := 0; j < len(candidate); j++ {
+		curr[0] = j + 1
+		c := candidate[j]
+		for i := 1; i <= len(q.query); i++ {
+			cost := 0
+			if q.query[i-1] != c {
+				cost = 1
+			}
+			curr[i] = min3(prev[i]+1, curr[i-1]+1, prev[i-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(q.query)]
+}
+
+// Distances scores every candidate, appending the distances to dst[:0] so
+// the same slice can be reused across batches
+func (q *CompiledQuery) Distances(candidates []string, dst []int) []int {
+	dst = dst[:0]
+	for _, candidate := range candidates {
+		dst = append(dst, q.Distance(candidate))
+	}
+	return dst
+}
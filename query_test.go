@@ -0,0 +1,94 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestCompiledQueryDistance(t *testing.T) {
+	tests := []struct {
+		query, candidate string
+	}{
+		{"", ""},
+		{"", "abc"},
+		{"abc", ""},
+		{"kitten", "sitting"},
+		{"saturday", "sunday"},
+		{"The quick brown fox jumps over the lazy dog", "The quick brown fox jumped over the lazy dogs"},
+		{strings.Repeat("ab", 32), strings.Repeat("ba", 32)},
+		{strings.Repeat("abc", 30), strings.Repeat("abd", 29)},
+	}
+
+	for _, tt := range tests {
+		got := CompileQuery(tt.query).Distance(tt.candidate)
+		want := LevenshteinDistance(tt.query, tt.candidate)
+		if got != want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.query, tt.candidate, got, want)
+		}
+	}
+}
+
+func TestCompiledQueryRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 500; iter++ {
+		query := randomString(rng, rng.Intn(80), "abcd")
+		compiled := CompileQuery(query)
+		for k := 0; k < 5; k++ {
+			candidate := randomString(rng, rng.Intn(80), "abcd")
+			got := compiled.Distance(candidate)
+			want := LevenshteinDistance(query, candidate)
+			if got != want {
+				t.Fatalf("Distance(%q, %q) = %d, want %d", query, candidate, got, want)
+			}
+		}
+	}
+}
+
+func TestCompiledQueryAllocations(t *testing.T) {
+	candidates := []string{"book", "books", "cake", "boo", "boon", "cook", "cape", "cart"}
+	dst := make([]int, 0, len(candidates))
+
+	for _, query := range []string{"bok", strings.Repeat("long query ", 10)} {
+		compiled := CompileQuery(query)
+		allocs := testing.AllocsPerRun(100, func() {
+			dst = compiled.Distances(candidates, dst)
+		})
+		if allocs != 0 {
+			t.Errorf("Distances for a %d byte query allocated %.0f times, want 0", len(query), allocs)
+		}
+	}
+}
+
+func BenchmarkCompiledQuery(b *testing.B) {
+	words := benchmarkWords()
+	compiled := CompileQuery("algoritm")
+	dst := make([]int, 0, len(words))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dst = compiled.Distances(words, dst)
+	}
+}
+
+func BenchmarkLevenshteinMany(b *testing.B) {
+	words := benchmarkWords()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, word := range words {
+			LevenshteinDistance("algoritm", word)
+		}
+	}
+}
+
+func benchmarkWords() []string {
+	rng := rand.New(rand.NewSource(1))
+	words := make([]string, 1000)
+	for i := range words {
+		words[i] = randomString(rng, 4+rng.Intn(8), "abcdefghijklmnopqrstuvwxyz")
+	}
+	return words
+}
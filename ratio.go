@@ -0,0 +1,179 @@
+package fuzzy
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Ratio scores two strings by their insertion/deletion distance, the measure
+// used by fuzzywuzzy and rapidfuzz. It is IndelSimilarity under the name
+// those libraries use, so all ratios here return scores in [0, 1].
+func Ratio(s1, s2 string) float64 {
+	return IndelSimilarity(s1, s2)
+}
+
+// PartialRatio scores the shorter string against the best matching window of
+// the longer one, so "Apple" matches "Apple Inc." perfectly
+func PartialRatio(s1, s2 string) float64 {
+	shorter, longer := s1, s2
+	if len(shorter) > len(longer) {
+		shorter, longer = longer, shorter
+	}
+	if len(shorter) == 0 {
+		if len(longer) == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	best := 0.0
+	for i := 0; i+len(shorter) <= len(longer); i++ {
+		score := Ratio(shorter, longer[i:i+len(shorter)])
+		if score > best {
+			best = score
+			if best == 1 {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// TokenSortRatio compares strings after lower-casing them, dropping
+// punctuation and sorting their words, so word order does not matter
+func TokenSortRatio(s1, s2 string) float64 {
+	return tokenSortRatio(s1, s2, Ratio)
+}
+
+// PartialTokenSortRatio is TokenSortRatio using PartialRatio on the sorted words
+func PartialTokenSortRatio(s1, s2 string) float64 {
+	return tokenSortRatio(s1, s2, PartialRatio)
+}
+
+// TokenSetRatio compares the words shared by both strings with each string's
+// full word set, so extra words in one of them do not lower the score
+func TokenSetRatio(s1, s2 string) float64 {
+	return tokenSetRatio(s1, s2, Ratio)
+}
+
+// PartialTokenSetRatio is TokenSetRatio using PartialRatio on the word sets
+func PartialTokenSetRatio(s1, s2 string) float64 {
+	return tokenSetRatio(s1, s2, PartialRatio)
+}
+
+// WeightedRatio combines the other ratios the way fuzzywuzzy's WRatio does:
+// partial ratios are preferred when the lengths differ a lot, and token
+// based ratios are slightly discounted against the plain ratio
+func WeightedRatio(s1, s2 string) float64 {
+	p1 := strings.Join(tokenize(s1), " ")
+	p2 := strings.Join(tokenize(s2), " ")
+	if p1 == "" || p2 == "" {
+		return 0
+	}
+
+	const unbaseScale = 0.95
+
+	best := Ratio(p1, p2)
+	lengthRatio := float64(maxInt(len(p1), len(p2))) / float64(min(len(p1), len(p2)))
+
+	if lengthRatio < 1.5 {
+		best = math.Max(best, TokenSortRatio(p1, p2)*unbaseScale)
+		best = math.Max(best, TokenSetRatio(p1, p2)*unbaseScale)
+		return best
+	}
+
+	partialScale := 0.9
+	if lengthRatio >= 8 {
+		partialScale = 0.6
+	}
+
+	best = math.Max(best, PartialRatio(p1, p2)*partialScale)
+	best = math.Max(best, PartialTokenSortRatio(p1, p2)*unbaseScale*partialScale)
+	best = math.Max(best, PartialTokenSetRatio(p1, p2)*unbaseScale*partialScale)
+	return best
+}
+
+func tokenSortRatio(s1, s2 string, ratio Similarity) float64 {
+	return ratio(sortedTokens(s1), sortedTokens(s2))
+}
+
+func sortedTokens(s string) string {
+	tokens := tokenize(s)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+func tokenSetRatio(s1, s2 string, ratio Similarity) float64 {
+	set1 := tokenSet(s1)
+	set2 := tokenSet(s2)
+	if len(set1) == 0 || len(set2) == 0 {
+		return 0
+	}
+
+	var shared, only1, only2 []string
+	for token := range set1 {
+		if _, ok := set2[token]; ok {
+			shared = append(shared, token)
+		} else {
+			only1 = append(only1, token)
+		}
+	}
+	for token := range set2 {
+		if _, ok := set1[token]; !ok {
+			only2 = append(only2, token)
+		}
+	}
+	sort.Strings(shared)
+	sort.Strings(only1)
+	sort.Strings(only2)
+
+	t0 := strings.Join(shared, " ")
+	t1 := strings.TrimSpace(t0 + " " + strings.Join(only1, " "))
+	t2 := strings.TrimSpace(t0 + " " + strings.Join(only2, " "))
+
+	best := ratio(t1, t2)
+	if t0 != "" {
+		best = math.Max(best, ratio(t0, t1))
+		best = math.Max(best, ratio(t0, t2))
+	}
+	return best
+}
+
+func tokenSet(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, token := range tokenize(s) {
+		set[token] = struct{}{}
+	}
+	return set
+}
+
+// ExtractResult is a choice scored by Extract
+type ExtractResult struct {
+	Choice string
+	Index  int // Position of the choice in the input slice
+	Score  float64
+}
+
+// Extract scores every choice against the query and returns the best ones,
+// highest score first and in input order among equal scores. A limit of
+// zero or less returns all choices.
+func Extract(query string, choices []string, scorer Similarity, limit int) []ExtractResult {
+	results := make([]ExtractResult, len(choices))
+	for i, choice := range choices {
+		results[i] = ExtractResult{
+			Choice: choice,
+			Index:  i,
+			Score:  scorer(query, choice),
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if limit > 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,82 @@
+package fuzzy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRatios(t *testing.T) {
+	tests := []struct {
+		name   string
+		scorer Similarity
+		s1, s2 string
+		want   float64
+	}{
+		{"Ratio", Ratio, "this is a test", "this is a test!", 28.0 / 29},
+		{"Ratio", Ratio, "", "", 1},
+		{"PartialRatio", PartialRatio, "Apple", "Apple Inc.", 1},
+		{"PartialRatio", PartialRatio, "", "abc", 0},
+		{"TokenSortRatio", TokenSortRatio, "Apple Inc.", "Inc Apple", 1},
+		{"TokenSortRatio", TokenSortRatio, "fuzzy wuzzy was a bear", "wuzzy fuzzy was a bear", 1},
+		{"TokenSetRatio", TokenSetRatio, "fuzzy was a bear", "fuzzy fuzzy was a bear", 1},
+		{"TokenSetRatio", TokenSetRatio, "new york mets", "new york mets vs atlanta braves", 1},
+		{"TokenSetRatio", TokenSetRatio, "", "abc", 0},
+		{"WeightedRatio", WeightedRatio, "Apple Inc.", "apple inc", 1},
+		{"WeightedRatio", WeightedRatio, "", "abc", 0},
+	}
+
+	for _, tt := range tests {
+		got := tt.scorer(tt.s1, tt.s2)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s(%q, %q) = %f, want %f", tt.name, tt.s1, tt.s2, got, tt.want)
+		}
+	}
+}
+
+func TestWeightedRatioPrefersPartialForLengthMismatch(t *testing.T) {
+	short := "new york mets"
+	long := "the new york mets beat the atlanta braves last night"
+
+	weighted := WeightedRatio(short, long)
+	if weighted <= Ratio(short, long) {
+		t.Errorf("WeightedRatio = %f, want more than Ratio = %f", weighted, Ratio(short, long))
+	}
+	if weighted > 0.9 {
+		t.Errorf("WeightedRatio = %f, want partial matches scaled to at most 0.9", weighted)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	choices := []string{"Atlanta Falcons", "New York Jets", "New York Giants", "Dallas Cowboys"}
+
+	results := Extract("new york jets", choices, WeightedRatio, 2)
+	if len(results) != 2 {
+		t.Fatalf("Extract returned %d results, want 2", len(results))
+	}
+	if results[0].Choice != "New York Jets" || results[0].Index != 1 || results[0].Score != 1 {
+		t.Errorf("best result = %+v, want New York Jets with score 1", results[0])
+	}
+	if results[1].Choice != "New York Giants" {
+		t.Errorf("second result = %+v, want New York Giants", results[1])
+	}
+
+	all := Extract("cowboys", choices, PartialRatio, 0)
+	if len(all) != len(choices) {
+		t.Errorf("Extract with no limit returned %d results, want %d", len(all), len(choices))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Score > all[i-1].Score {
+			t.Errorf("results not sorted by score: %+v", all)
+		}
+	}
+}
+
+func BenchmarkWeightedRatio(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "lazy dog jumped over by the quick brown fox"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		WeightedRatio(s1, s2)
+	}
+}
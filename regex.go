@@ -0,0 +1,305 @@
+package fuzzy
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxLiteralSet bounds the number of alternative literals tracked while
+// analyzing a regexp, such as the 4 strings of "conn(ect|ection) re(set|fused)"
+const maxLiteralSet = 64
+
+// maxClassLiterals bounds the size of the character classes spelled out as
+// literals, since wide classes such as \w are matched almost everywhere
+const maxClassLiterals = 8
+
+// RegexSearch returns the leftmost non-overlapping matches of re in the text,
+// the same ranges as re.FindAllStringIndex, ordered by Start.
+//
+// When every match must contain one of a set of literals, those literals are
+// located with the suffix array and re only runs on the lines holding them.
+// Otherwise, and when a match could span lines or depends on the start or
+// end of the whole text, the text is scanned in full.
+func (sa *SuffixArray) RegexSearch(re *regexp.Regexp) []Match {
+	literals := requiredLiterals(re)
+	if literals == nil {
+		return indexesToMatches(re.FindAllStringIndex(sa.text, -1), 0)
+	}
+
+	var positions []int
+	for _, literal := range literals {
+		positions = append(positions, sa.Search(literal)...)
+	}
+	sort.Ints(positions)
+
+	var results []Match
+	lineEnd := -1
+	for _, p := range positions {
+		if p < lineEnd {
+			continue // this line was already searched
+		}
+		lineStart := strings.LastIndexByte(sa.text[:p], '\n') + 1
+		lineEnd = len(sa.text)
+		if i := strings.IndexByte(sa.text[p:], '\n'); i >= 0 {
+			lineEnd = p + i
+		}
+		line := sa.text[lineStart:lineEnd]
+		results = append(results, indexesToMatches(re.FindAllStringIndex(line, -1), lineStart)...)
+	}
+
+	return results
+}
+
+func indexesToMatches(indexes [][]int, offset int) []Match {
+	if len(indexes) == 0 {
+		return nil
+	}
+	matches := make([]Match, len(indexes))
+	for i, loc := range indexes {
+		matches[i] = Match{Start: offset + loc[0], End: offset + loc[1]}
+	}
+	return matches
+}
+
+// requiredLiterals returns non-empty literals such that every match of re
+// contains at least one of them and lies within a single line, or nil when
+// there are none or a match may cross a newline or depend on the text edges
+func requiredLiterals(re *regexp.Regexp) []string {
+	tree, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return nil
+	}
+	tree = tree.Simplify()
+	if !lineLocal(tree) {
+		return nil
+	}
+
+	info := analyzeLiterals(tree)
+	if minLength(info.required) <= 0 {
+		return nil
+	}
+	// re matches U+FFFD against any invalid UTF-8 byte, not only against
+	// its own encoding, so such literals cannot be searched for
+	for _, literal := range info.required {
+		if strings.ContainsRune(literal, utf8.RuneError) {
+			return nil
+		}
+	}
+	return info.required
+}
+
+// lineLocal reports whether matches of the tree can neither contain a
+// newline nor depend on the start or end of the whole text
+func lineLocal(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpAnyChar, syntax.OpBeginText, syntax.OpEndText:
+		return false
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if r == '\n' {
+				return false
+			}
+		}
+	case syntax.OpCharClass:
+		for i := 0; i < len(re.Rune); i += 2 {
+			if re.Rune[i] <= '\n' && '\n' <= re.Rune[i+1] {
+				return false
+			}
+		}
+	}
+
+	for _, sub := range re.Sub {
+		if !lineLocal(sub) {
+			return false
+		}
+	}
+	return true
+}
+
+// literalInfo describes the strings matched by a regexp node. exact lists
+// every string the node can match, when that set is small. required lists
+// literals one of which occurs in every match.
+type literalInfo struct {
+	exact    []string
+	required []string
+}
+
+func analyzeLiterals(re *syntax.Regexp) literalInfo {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return exactInfo([]string{""})
+
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase == 0 {
+			return exactInfo([]string{string(re.Rune)})
+		}
+		// Spell out the case variants of case-insensitive literals, or of
+		// their longest prefix with few enough variants
+		set := []string{""}
+		for _, r := range re.Rune {
+			variants := []string{string(r)}
+			for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+				variants = append(variants, string(f))
+			}
+			next := product(set, variants)
+			if next == nil {
+				return literalInfo{required: set}
+			}
+			set = next
+		}
+		return exactInfo(set)
+
+	case syntax.OpCharClass:
+		var set []string
+		for i := 0; i < len(re.Rune); i += 2 {
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				if len(set) == maxClassLiterals {
+					return literalInfo{}
+				}
+				set = append(set, string(r))
+			}
+		}
+		return exactInfo(set)
+
+	case syntax.OpCapture:
+		return analyzeLiterals(re.Sub[0])
+
+	case syntax.OpQuest:
+		sub := analyzeLiterals(re.Sub[0])
+		if sub.exact == nil {
+			return literalInfo{}
+		}
+		return exactInfo(union([]string{""}, sub.exact))
+
+	case syntax.OpPlus:
+		// Every match contains at least one match of the operand
+		return literalInfo{required: analyzeLiterals(re.Sub[0]).required}
+
+	case syntax.OpConcat:
+		return analyzeConcat(re.Sub)
+
+	case syntax.OpAlternate:
+		var exact, required []string
+		exactOK, requiredOK := true, true
+		for _, sub := range re.Sub {
+			info := analyzeLiterals(sub)
+			exact, exactOK = extendSet(exact, info.exact, exactOK)
+			required, requiredOK = extendSet(required, info.required, requiredOK)
+		}
+		if exactOK {
+			return exactInfo(exact)
+		}
+		if requiredOK {
+			return literalInfo{required: required}
+		}
+	}
+
+	return literalInfo{}
+}
+
+// analyzeConcat combines the operands of a concatenation. Runs of operands
+// with exact sets are multiplied out, and the best of those runs and of the
+// other operands' required sets is kept.
+func analyzeConcat(subs []*syntax.Regexp) literalInfo {
+	var best []string
+	consider := func(set []string) {
+		if set != nil && (best == nil || minLength(set) > minLength(best)) {
+			best = set
+		}
+	}
+
+	run := []string{""}
+	allExact := true
+	for _, sub := range subs {
+		info := analyzeLiterals(sub)
+		if info.exact != nil {
+			if next := product(run, info.exact); next != nil {
+				run = next
+				continue
+			}
+		}
+
+		// The run ends here, either at a non-exact operand or because
+		// its product grew too large
+		allExact = false
+		consider(run)
+		consider(info.required)
+		run = info.exact
+		if run == nil {
+			run = []string{""}
+		}
+	}
+
+	if allExact {
+		return exactInfo(run)
+	}
+	consider(run)
+	return literalInfo{required: best}
+}
+
+func exactInfo(set []string) literalInfo {
+	return literalInfo{exact: set, required: set}
+}
+
+// extendSet adds set to acc while both are known and small, reporting
+// whether acc is still known
+func extendSet(acc, set []string, ok bool) ([]string, bool) {
+	if !ok || set == nil {
+		return nil, false
+	}
+	acc = union(acc, set)
+	if len(acc) > maxLiteralSet {
+		return nil, false
+	}
+	return acc, true
+}
+
+func union(a, b []string) []string {
+	out := append([]string{}, a...)
+	for _, s := range b {
+		found := false
+		for _, t := range out {
+			if s == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// product returns every concatenation of a string of a with one of b, or
+// nil when there would be more than maxLiteralSet
+func product(a, b []string) []string {
+	if len(a)*len(b) > maxLiteralSet {
+		return nil
+	}
+	out := make([]string, 0, len(a)*len(b))
+	for _, x := range a {
+		for _, y := range b {
+			out = append(out, x+y)
+		}
+	}
+	return union(nil, out)
+}
+
+// minLength returns the length of the shortest string in set, or -1 for an
+// empty set
+func minLength(set []string) int {
+	shortest := -1
+	for _, s := range set {
+		if shortest < 0 || len(s) < shortest {
+			shortest = len(s)
+		}
+	}
+	return shortest
+}
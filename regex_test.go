@@ -0,0 +1,132 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRequiredLiterals(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`error`, []string{"error"}},
+		{`conn(ect|ection) (refused|reset)`, []string{"connect refused", "connect reset", "connection refused", "connection reset"}},
+		{`timeout after \d+ms`, []string{"timeout after "}},
+		{`\d+ retries`, []string{" retries"}},
+		{`(?i)fail`, []string{"FAIL", "FAIl", "FAiL", "FAil", "FaIL", "FaIl", "FaiL", "Fail", "fAIL", "fAIl", "fAiL", "fAil", "faIL", "faIl", "faiL", "fail"}},
+		{`[Ee]rror`, []string{"Error", "error"}},
+		{`(warn|error)+:`, []string{"error", "warn"}},
+		{`(?m)^panic: .*$`, []string{"panic: "}},
+		{`colou?r`, []string{"color", "colour"}},
+		{`a(b|c*)d`, []string{"a"}},
+		{`\w+`, nil},
+		{`x*`, nil},
+		{`^error`, nil},          // depends on the start of the text
+		{`begin(.|\n)*end`, nil}, // may span lines
+		{`begin[^;]*end`, nil},   // [^;] matches newlines
+		{"a\uFFFDb", nil},        // U+FFFD also matches invalid UTF-8
+		{`[\x{FFFD}x]y`, nil},
+	}
+
+	for _, tt := range tests {
+		got := requiredLiterals(regexp.MustCompile(tt.expr))
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("requiredLiterals(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestRegexSearch(t *testing.T) {
+	logs := strings.Join([]string{
+		"12:00 connect refused by 10.0.0.1",
+		"12:01 connection reset by peer",
+		"12:02 CONNECTION REFUSED",
+		"12:03 connection refused, connection reset",
+		"12:04 timeout after 350ms",
+		"12:05 retrying connect",
+		"12:06 ab\xff\xfe",
+		"",
+	}, "\n")
+	sa := NewSuffixArray(logs)
+
+	exprs := []string{
+		`conn(ect|ection) (refused|reset)`,
+		`(?i)connection refused`,
+		`timeout after \d+ms`,
+		`\d\d:\d\d`,
+		`(?m)^12:0[0-3]`,
+		`(?m)reset.*$`,
+		`refused\nby`,
+		`\bby\b`,
+		`^12`,
+		`missing`,
+		`connect(ion)?`,
+		"ab\uFFFD",
+	}
+
+	for _, expr := range exprs {
+		re := regexp.MustCompile(expr)
+		want := indexesToMatches(re.FindAllStringIndex(logs, -1), 0)
+		if got := sa.RegexSearch(re); !reflect.DeepEqual(got, want) {
+			t.Errorf("RegexSearch(%q) = %v, want %v", expr, got, want)
+		}
+	}
+}
+
+func TestRegexSearchRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	exprs := []string{
+		`ab`, `a(b|c)a`, `(ab|ba)+c`, `b[ac]?b`, `(?i)abc`, `a.b`, `\bab`,
+		`(?m)^ab`, `(?m)ba$`, `c\nab`, `a[^c]b`, `(abc|b)`, `a{2,3}b`,
+	}
+
+	for iter := 0; iter < 100; iter++ {
+		text := randomString(rng, rng.Intn(300), "abcA \n")
+		sa := NewSuffixArray(text)
+		for _, expr := range exprs {
+			re := regexp.MustCompile(expr)
+			want := indexesToMatches(re.FindAllStringIndex(text, -1), 0)
+			if got := sa.RegexSearch(re); !reflect.DeepEqual(got, want) {
+				t.Fatalf("RegexSearch(%q) in %q = %v, want %v", expr, text, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkRegexSearch(b *testing.B) {
+	words := benchmarkWords()
+	var sb strings.Builder
+	for i := 0; sb.Len() < 1<<20; i++ {
+		sb.WriteString(words[i%len(words)])
+		if i%8 == 7 {
+			sb.WriteByte('\n')
+		} else {
+			sb.WriteByte(' ')
+		}
+	}
+	text := sb.String() + "connection refused\n"
+	sa := NewSuffixArray(text)
+	re := regexp.MustCompile(`conn(ect|ection) (refused|reset)`)
+
+	b.Run("SuffixArray", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = sa.RegexSearch(re)
+		}
+	})
+
+	b.Run("Scan", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			_ = re.FindAllStringIndex(text, -1)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package fuzzy
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Metric describes a named string comparison so it can be selected from
+// configuration and recorded alongside the indexes built with it
+type Metric struct {
+	Name string
+
+	// Distance counts differences between two strings. It is nil for metrics
+	// that only produce real valued scores, such as Jaro-Winkler.
+	Distance DistanceFunc
+
+	// Similarity scores two strings in [0, 1], where 1 means identical
+	Similarity Similarity
+
+	// IsMetric reports whether Distance is a true metric: it is zero only
+	// for equal strings, symmetric, and satisfies the triangle inequality.
+	// Only true metrics can be used with BKTree.
+	IsMetric bool
+
+	// Integer reports whether the raw values are integer edit counts rather
+	// than real valued scores
+	Integer bool
+
+	// Normalized reports whether the raw values already lie in [0, 1]
+	Normalized bool
+}
+
+var (
+	metricsMu sync.RWMutex
+	metrics   = make(map[string]Metric)
+)
+
+func init() {
+	builtin := []Metric{
+		{Name: "levenshtein", Distance: LevenshteinDistance, Similarity: LevenshteinSimilarity, IsMetric: true, Integer: true},
+		{Name: "damerau", Distance: UnrestrictedDamerauLevenshteinDistance, Similarity: DamerauLevenshteinSimilarity, IsMetric: true, Integer: true},
+		{Name: "damerau-osa", Distance: OSADistance, Similarity: OSASimilarity, Integer: true},
+		{Name: "indel", Distance: MyersDistance, Similarity: IndelSimilarity, IsMetric: true, Integer: true},
+		{Name: "word-levenshtein", Distance: WordDistance, Similarity: DistanceSimilarity(WordDistance, wordCountBound), Integer: true},
+		{Name: "qgram", Distance: qgramDistance, Similarity: QGramSimilarity(2), Integer: true},
+		{Name: "qgram-cosine", Similarity: QGramCosineSimilarity(2), Normalized: true},
+		{Name: "jaro", Similarity: JaroSimilarity, Normalized: true},
+		{Name: "jaro-winkler", Similarity: JaroWinklerSimilarity, Normalized: true},
+		{Name: "ratio", Similarity: Ratio, Normalized: true},
+		{Name: "partial-ratio", Similarity: PartialRatio, Normalized: true},
+		{Name: "token-sort-ratio", Similarity: TokenSortRatio, Normalized: true},
+		{Name: "token-set-ratio", Similarity: TokenSetRatio, Normalized: true},
+		{Name: "weighted-ratio", Similarity: WeightedRatio, Normalized: true},
+	}
+
+	for _, m := range builtin {
+		if err := RegisterMetric(m); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterMetric makes a metric available by name. Names must be unique and
+// every metric needs a Similarity; only metrics with a Distance can be true
+// metrics.
+func RegisterMetric(m Metric) error {
+	if m.Name == "" {
+		return errors.New("metric has no name")
+	}
+	if m.Similarity == nil {
+		return fmt.Errorf("metric %q has no similarity", m.Name)
+	}
+	if m.IsMetric && m.Distance == nil {
+		return fmt.Errorf("metric %q is marked as a true metric but has no distance", m.Name)
+	}
+
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if _, ok := metrics[m.Name]; ok {
+		return fmt.Errorf("metric %q is already registered", m.Name)
+	}
+	metrics[m.Name] = m
+	return nil
+}
+
+// unregisterMetric removes a metric registered by a test
+func unregisterMetric(name string) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	delete(metrics, name)
+}
+
+// LookupMetric returns the metric registered under name
+func LookupMetric(name string) (Metric, bool) {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	m, ok := metrics[name]
+	return m, ok
+}
+
+// MetricNames returns the names of all registered metrics in sorted order
+func MetricNames() []string {
+	metricsMu.RLock()
+	defer metricsMu.RUnlock()
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func qgramDistance(s1, s2 string) int {
+	return int(NewQGram(s1, 2).Distance(NewQGram(s2, 2)))
+}
+
+func wordCountBound(s1, s2 string) int {
+	return maxInt(len(tokenize(s1)), len(tokenize(s2)))
+}
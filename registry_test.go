@@ -0,0 +1,115 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestLookupMetric(t *testing.T) {
+	for _, name := range []string{"levenshtein", "damerau", "damerau-osa", "jaro-winkler", "qgram-cosine"} {
+		m, ok := LookupMetric(name)
+		if !ok {
+			t.Fatalf("LookupMetric(%q) not found", name)
+		}
+		if m.Name != name {
+			t.Errorf("LookupMetric(%q).Name = %q", name, m.Name)
+		}
+	}
+
+	if _, ok := LookupMetric("no-such-metric"); ok {
+		t.Error("LookupMetric found an unregistered name")
+	}
+}
+
+func TestRegisteredMetricsConsistent(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, name := range MetricNames() {
+		m, _ := LookupMetric(name)
+		if m.Integer == (m.Distance == nil) {
+			t.Errorf("%s: Integer = %v but Distance is nil = %v", name, m.Integer, m.Distance == nil)
+		}
+
+		for iter := 0; iter < 50; iter++ {
+			s1 := randomString(rng, rng.Intn(8), "ab ")
+			s2 := randomString(rng, rng.Intn(8), "ab ")
+			s3 := randomString(rng, rng.Intn(8), "ab ")
+
+			if score := m.Similarity(s1, s2); score < 0 || score > 1 {
+				t.Fatalf("%s: Similarity(%q, %q) = %f, outside [0, 1]", name, s1, s2, score)
+			}
+			if !m.IsMetric {
+				continue
+			}
+			if (m.Distance(s1, s2) == 0) != (s1 == s2) {
+				t.Fatalf("%s: Distance(%q, %q) = %d", name, s1, s2, m.Distance(s1, s2))
+			}
+			if m.Distance(s1, s3) > m.Distance(s1, s2)+m.Distance(s2, s3) {
+				t.Fatalf("%s violates the triangle inequality on %q, %q, %q", name, s1, s2, s3)
+			}
+		}
+	}
+}
+
+func TestRegisterMetric(t *testing.T) {
+	tests := []struct {
+		name   string
+		metric Metric
+	}{
+		{"no name", Metric{Similarity: Ratio}},
+		{"no similarity", Metric{Name: "test-no-similarity"}},
+		{"metric without distance", Metric{Name: "test-no-distance", Similarity: Ratio, IsMetric: true}},
+		{"duplicate", Metric{Name: "levenshtein", Similarity: Ratio}},
+	}
+
+	for _, tt := range tests {
+		if err := RegisterMetric(tt.metric); err == nil {
+			t.Errorf("RegisterMetric(%s) succeeded, want error", tt.name)
+		}
+	}
+
+	lengthMetric := Metric{
+		Name:       "test-length",
+		Distance:   func(s1, s2 string) int { return maxInt(len(s1), len(s2)) - min(len(s1), len(s2)) },
+		Similarity: func(s1, s2 string) float64 { return 1 },
+		Integer:    true,
+	}
+	if err := RegisterMetric(lengthMetric); err != nil {
+		t.Fatalf("RegisterMetric: %v", err)
+	}
+	t.Cleanup(func() { unregisterMetric(lengthMetric.Name) })
+	if _, ok := LookupMetric("test-length"); !ok {
+		t.Error("registered metric not found")
+	}
+}
+
+func TestNewBKTreeWithMetric(t *testing.T) {
+	tree, err := NewBKTreeWithMetric("damerau")
+	if err != nil {
+		t.Fatalf("NewBKTreeWithMetric: %v", err)
+	}
+	if tree.Metric() != "damerau" {
+		t.Errorf("Metric() = %q, want damerau", tree.Metric())
+	}
+
+	for _, word := range []string{"abc", "ca", "cab"} {
+		tree.Add(word)
+	}
+	got := tree.Search("acb", 1)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"abc", "cab"}) {
+		t.Errorf("Search = %v, want [abc cab]", got)
+	}
+
+	for _, name := range []string{"damerau-osa", "jaro-winkler", "unknown"} {
+		if _, err := NewBKTreeWithMetric(name); err == nil {
+			t.Errorf("NewBKTreeWithMetric(%q) succeeded, want error", name)
+		}
+	}
+
+	if NewBKTree().Metric() != "levenshtein" {
+		t.Errorf("NewBKTree().Metric() = %q, want levenshtein", NewBKTree().Metric())
+	}
+}
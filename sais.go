@@ -0,0 +1,170 @@
+package fuzzy
+
+import "fmt"
+
+// SortSuffixes returns the suffix array of text over an integer alphabet:
+// the start positions of all suffixes in lexicographic order, where a suffix
+// sorts before the longer suffixes it is a prefix of. Every value in text
+// must lie in [0, alphabetSize). It runs in O(n + alphabetSize) time using
+// the SA-IS algorithm.
+func SortSuffixes(text []int, alphabetSize int) []int {
+	for i, c := range text {
+		if c < 0 || c >= alphabetSize {
+			panic(fmt.Sprintf("fuzzy: SortSuffixes symbol %d at %d outside alphabet of size %d", c, i, alphabetSize))
+		}
+	}
+	return sais(text, alphabetSize-1)
+}
+
+// sais builds the suffix array of s, whose symbols lie in [0, upper], by
+// induced sorting (Nong, Zhang and Chan). The end of the text acts as a
+// virtual sentinel smaller than every symbol, so none has to be appended.
+func sais[T byte | int](s []T, upper int) []int {
+	n := len(s)
+	switch n {
+	case 0:
+		return []int{}
+	case 1:
+		return []int{0}
+	case 2:
+		if s[0] < s[1] {
+			return []int{0, 1}
+		}
+		return []int{1, 0}
+	}
+
+	sa := make([]int, n)
+
+	// ls[i] is true when suffix i is S-type, i.e. smaller than suffix i+1
+	ls := make([]bool, n)
+	for i := n - 2; i >= 0; i-- {
+		if s[i] == s[i+1] {
+			ls[i] = ls[i+1]
+		} else {
+			ls[i] = s[i] < s[i+1]
+		}
+	}
+
+	// sumL[c] and sumS[c] are the starts of the L-type and S-type parts of
+	// the bucket for symbol c
+	sumL := make([]int, upper+2)
+	sumS := make([]int, upper+2)
+	for i := 0; i < n; i++ {
+		if !ls[i] {
+			sumS[s[i]]++
+		} else {
+			sumL[int(s[i])+1]++
+		}
+	}
+	for c := 0; c <= upper; c++ {
+		sumS[c] += sumL[c]
+		sumL[c+1] += sumS[c]
+	}
+
+	buf := make([]int, upper+2)
+	induce := func(lms []int) {
+		for i := range sa {
+			sa[i] = -1
+		}
+
+		// Place LMS suffixes at the start of their S buckets
+		copy(buf, sumS)
+		for _, d := range lms {
+			if d == n {
+				continue
+			}
+			sa[buf[s[d]]] = d
+			buf[s[d]]++
+		}
+
+		// Induce L-type suffixes left to right, starting from the last
+		// suffix which follows the virtual sentinel
+		copy(buf, sumL)
+		sa[buf[s[n-1]]] = n - 1
+		buf[s[n-1]]++
+		for i := 0; i < n; i++ {
+			v := sa[i]
+			if v >= 1 && !ls[v-1] {
+				sa[buf[s[v-1]]] = v - 1
+				buf[s[v-1]]++
+			}
+		}
+
+		// Induce S-type suffixes right to left from the bucket ends
+		copy(buf, sumL)
+		for i := n - 1; i >= 0; i-- {
+			v := sa[i]
+			if v >= 1 && ls[v-1] {
+				buf[int(s[v-1])+1]--
+				sa[buf[int(s[v-1])+1]] = v - 1
+			}
+		}
+	}
+
+	// lmsMap numbers the leftmost S-type positions in text order
+	lmsMap := make([]int, n+1)
+	for i := range lmsMap {
+		lmsMap[i] = -1
+	}
+	var lms []int
+	for i := 1; i < n; i++ {
+		if !ls[i-1] && ls[i] {
+			lmsMap[i] = len(lms)
+			lms = append(lms, i)
+		}
+	}
+	m := len(lms)
+
+	induce(lms)
+
+	if m == 0 {
+		return sa
+	}
+
+	// Name the LMS substrings in sorted order; equal substrings share a name
+	sortedLMS := make([]int, 0, m)
+	for _, v := range sa {
+		if lmsMap[v] != -1 {
+			sortedLMS = append(sortedLMS, v)
+		}
+	}
+
+	reduced := make([]int, m)
+	name := 0
+	reduced[lmsMap[sortedLMS[0]]] = 0
+	for i := 1; i < m; i++ {
+		l, r := sortedLMS[i-1], sortedLMS[i]
+		endL, endR := n, n
+		if lmsMap[l]+1 < m {
+			endL = lms[lmsMap[l]+1]
+		}
+		if lmsMap[r]+1 < m {
+			endR = lms[lmsMap[r]+1]
+		}
+
+		same := endL-l == endR-r
+		if same {
+			for l < endL && s[l] == s[r] {
+				l++
+				r++
+			}
+			if l == n || r == n || s[l] != s[r] {
+				same = false
+			}
+		}
+		if !same {
+			name++
+		}
+		reduced[lmsMap[sortedLMS[i]]] = name
+	}
+
+	// Sort the reduced problem recursively and induce the final order from
+	// the correctly sorted LMS suffixes
+	reducedSA := sais(reduced, name)
+	for i := 0; i < m; i++ {
+		sortedLMS[i] = lms[reducedSA[i]]
+	}
+	induce(sortedLMS)
+
+	return sa
+}
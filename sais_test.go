@@ -0,0 +1,107 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// naiveSuffixArray sorts suffixes by direct comparison
+func naiveSuffixArray(text string) []int {
+	suffixes := make([]int, len(text))
+	for i := range suffixes {
+		suffixes[i] = i
+	}
+	sort.Slice(suffixes, func(i, j int) bool {
+		return text[suffixes[i]:] < text[suffixes[j]:]
+	})
+	return suffixes
+}
+
+func TestSuffixArrayMatchesNaive(t *testing.T) {
+	texts := []string{
+		"",
+		"a",
+		"ab",
+		"ba",
+		"banana",
+		"mississippi",
+		"mississippi$",
+		strings.Repeat("a", 100),
+		strings.Repeat("ab", 50),
+		strings.Repeat("abcab", 20) + "c",
+		"The quick brown fox jumps over the lazy dog",
+		"\x00\xff\x00\xff\x01",
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		texts = append(texts, randomString(rng, rng.Intn(200), "ab"[:1+rng.Intn(2)]+"cd"[:rng.Intn(3)]))
+	}
+
+	for _, text := range texts {
+		got := NewSuffixArray(text).suffixes
+		want := naiveSuffixArray(text)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("suffix array of %q = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestSortSuffixes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		k := 1 + rng.Intn(1000)
+		text := make([]int, rng.Intn(100))
+		for i := range text {
+			text[i] = rng.Intn(k)
+		}
+
+		got := SortSuffixes(text, k)
+
+		want := make([]int, len(text))
+		for i := range want {
+			want[i] = i
+		}
+		sort.Slice(want, func(i, j int) bool {
+			a, b := text[want[i]:], text[want[j]:]
+			for x := 0; x < len(a) && x < len(b); x++ {
+				if a[x] != b[x] {
+					return a[x] < b[x]
+				}
+			}
+			return len(a) < len(b)
+		})
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("SortSuffixes(%v, %d) = %v, want %v", text, k, got, want)
+		}
+	}
+}
+
+func TestSortSuffixesOutOfAlphabet(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("SortSuffixes did not panic on a symbol outside the alphabet")
+		}
+	}()
+	SortSuffixes([]int{0, 3, 1}, 3)
+}
+
+func BenchmarkNewSuffixArray(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for sb.Len() < 1<<20 {
+		sb.WriteString(randomString(rng, 3+rng.Intn(8), "abcdefghijklmnopqrstuvwxyz"))
+		sb.WriteByte('\n')
+	}
+	text := sb.String()
+
+	b.SetBytes(int64(len(text)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewSuffixArray(text)
+	}
+}
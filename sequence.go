@@ -0,0 +1,217 @@
+package fuzzy
+
+// Levenshtein calculates the edit distance between two sequences of any
+// comparable element type, such as words, log tokens or AST node kinds
+func Levenshtein[T comparable](a, b []T) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) == 0 {
+		return len(b)
+	}
+
+	prev := make([]int, len(a)+1)
+	curr := make([]int, len(a)+1)
+	for i := range prev {
+		prev[i] = i
+	}
+
+	for j := 1; j <= len(b); j++ {
+		curr[0] = j
+		for i := 1; i <= len(a); i++ {
+			cost := 0
+			if a[i-1] != b[j-1] {
+				cost = 1
+			}
+			curr[i] = min3(
+				prev[i]+1,      // deletion
+				curr[i-1]+1,    // insertion
+				prev[i-1]+cost, // substitution
+			)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(a)]
+}
+
+// Damerau calculates the unrestricted Damerau-Levenshtein distance between
+// two sequences using the Lowrance-Wagner algorithm. Unlike OSA, edits may be
+// applied to already transposed elements, which makes it a metric.
+func Damerau[T comparable](a, b []T) int {
+	// Number the distinct elements, so the algorithm compares ints and
+	// keeps the last row of a in which each element was seen in a slice
+	ids := make(map[T]int)
+	number := func(seq []T) []int {
+		out := make([]int, len(seq))
+		for i, e := range seq {
+			id, ok := ids[e]
+			if !ok {
+				id = len(ids)
+				ids[e] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+	aIDs, bIDs := number(a), number(b)
+	return lowranceWagner(aIDs, bIDs, make([]int, len(ids)))
+}
+
+// lowranceWagner is the Damerau-Levenshtein core shared by Damerau and
+// UnrestrictedDamerauLevenshteinDistance. Elements index lastRow, which
+// must have room for each of them and be zeroed.
+func lowranceWagner[E byte | int](a, b []E, lastRow []int) int {
+	len1 := len(a)
+	len2 := len(b)
+
+	if len1 == 0 {
+		return len2
+	}
+	if len2 == 0 {
+		return len1
+	}
+
+	// Matrix with an extra border row and column holding maxDist, so that
+	// transpositions reaching before the start of a sequence never win.
+	// Cell (i, j) is stored at i*cols+j.
+	maxDist := len1 + len2
+	cols := len2 + 2
+	matrix := make([]int, (len1+2)*cols)
+
+	matrix[0] = maxDist
+	for i := 0; i <= len1; i++ {
+		matrix[(i+1)*cols] = maxDist
+		matrix[(i+1)*cols+1] = i
+	}
+	for j := 0; j <= len2; j++ {
+		matrix[j+1] = maxDist
+		matrix[cols+j+1] = j
+	}
+
+	for i := 1; i <= len1; i++ {
+		// Last column of b in this row where a[i-1] matched
+		lastMatchCol := 0
+		prev := matrix[i*cols : (i+1)*cols]
+		curr := matrix[(i+1)*cols : (i+2)*cols]
+		for j := 1; j <= len2; j++ {
+			k := lastRow[b[j-1]]
+			l := lastMatchCol
+
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+				lastMatchCol = j
+			}
+
+			curr[j+1] = min(
+				min3(
+					prev[j]+cost, // substitution
+					curr[j]+1,    // insertion
+					prev[j+1]+1,  // deletion
+				),
+				matrix[k*cols+l]+(i-k-1)+1+(j-l-1), // transposition
+			)
+		}
+		lastRow[a[i-1]] = i
+	}
+
+	return matrix[(len1+1)*cols+len2+1]
+}
+
+// LCS returns a longest subsequence of elements shared by a and b in the
+// same order, the generic form of LongestCommonSubsequence
+func LCS[T comparable](a, b []T) []T {
+	n := len(a)
+	m := len(b)
+
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			if a[i-1] == b[j-1] {
+				table[i][j] = table[i-1][j-1] + 1
+			} else {
+				table[i][j] = maxInt(table[i-1][j], table[i][j-1])
+			}
+		}
+	}
+
+	// Walk back from the bottom right corner to recover the elements
+	seq := make([]T, table[n][m])
+	k := len(seq)
+	for i, j := n, m; i > 0 && j > 0; {
+		switch {
+		case a[i-1] == b[j-1]:
+			k--
+			seq[k] = a[i-1]
+			i--
+			j--
+		case table[i-1][j] >= table[i][j-1]:
+			i--
+		default:
+			j--
+		}
+	}
+
+	return seq
+}
+
+// WordDistance is the Levenshtein distance between the words of two strings,
+// counting inserted, deleted and replaced words. Words are lower-cased and
+// split on anything that is not a letter or digit.
+func WordDistance(s1, s2 string) int {
+	return Levenshtein(tokenize(s1), tokenize(s2))
+}
+
+// SequenceBKTree is a BKTree holding sequences of any comparable element
+// type instead of strings
+type SequenceBKTree[T comparable] struct {
+	tree     bkTree[[]T]
+	distance func(a, b []T) int
+}
+
+// SequenceResult contains a sequence and its distance from the query. Score
+// normalizes Distance by the length of the longer sequence into [0, 1], as
+// SearchResult.Score does for strings.
+type SequenceResult[T comparable] struct {
+	Sequence []T
+	Distance int
+	Score    float64
+}
+
+// NewSequenceBKTree creates a BK-tree over sequences using the given distance,
+// typically Levenshtein or Damerau. The distance must be a metric for
+// searches to be exact.
+func NewSequenceBKTree[T comparable](distance func(a, b []T) int) *SequenceBKTree[T] {
+	return &SequenceBKTree[T]{distance: distance}
+}
+
+// Add inserts a sequence into the tree
+func (t *SequenceBKTree[T]) Add(seq []T) {
+	t.tree.add(seq, t.distance)
+}
+
+// Search finds all sequences within maxDistance of the query
+func (t *SequenceBKTree[T]) Search(query []T, maxDistance int) []SequenceResult[T] {
+	var results []SequenceResult[T]
+	distance := func(seq []T) int {
+		return t.distance(seq, query)
+	}
+	t.tree.search(distance, maxDistance, func(seq []T, dist int) {
+		results = append(results, SequenceResult[T]{
+			Sequence: seq,
+			Distance: dist,
+			Score:    normalizeDistance(dist, maxInt(len(seq), len(query))),
+		})
+	})
+	return results
+}
+
+// Size returns the number of sequences in the tree
+func (t *SequenceBKTree[T]) Size() int {
+	return t.tree.size
+}
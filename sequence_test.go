@@ -0,0 +1,148 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestGenericDistancesMatchStrings(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 300; iter++ {
+		s1 := randomString(rng, rng.Intn(15), "abc")
+		s2 := randomString(rng, rng.Intn(15), "abc")
+		b1, b2 := []byte(s1), []byte(s2)
+
+		if got, want := Levenshtein(b1, b2), LevenshteinDistance(s1, s2); got != want {
+			t.Fatalf("Levenshtein(%q, %q) = %d, want %d", s1, s2, got, want)
+		}
+		if got, want := Damerau(b1, b2), UnrestrictedDamerauLevenshteinDistance(s1, s2); got != want {
+			t.Fatalf("Damerau(%q, %q) = %d, want %d", s1, s2, got, want)
+		}
+		if got, want := len(LCS(b1, b2)), LCSLength(s1, s2); got != want {
+			t.Fatalf("len(LCS(%q, %q)) = %d, want %d", s1, s2, got, want)
+		}
+	}
+}
+
+func TestWordDistance(t *testing.T) {
+	tests := []struct {
+		s1, s2 string
+		want   int
+	}{
+		{"", "", 0},
+		{"the quick brown fox", "the quick brown fox", 0},
+		{"the quick brown fox", "The quick, brown fox!", 0},
+		{"the quick brown fox", "the slow brown fox", 1},
+		{"the quick brown fox", "quick brown fox jumps", 2},
+		{"hello world", "world hello", 2},
+	}
+
+	for _, tt := range tests {
+		if got := WordDistance(tt.s1, tt.s2); got != tt.want {
+			t.Errorf("WordDistance(%q, %q) = %d, want %d", tt.s1, tt.s2, got, tt.want)
+		}
+	}
+
+	if got := Damerau(strings.Fields("hello world"), strings.Fields("world hello")); got != 1 {
+		t.Errorf("Damerau on swapped words = %d, want 1", got)
+	}
+}
+
+func TestLCSTokens(t *testing.T) {
+	type kind int
+	a := []kind{1, 2, 3, 2, 4, 1, 2}
+	b := []kind{2, 4, 3, 1, 2, 1}
+
+	got := LCS(a, b)
+	if len(got) != 4 {
+		t.Fatalf("LCS = %v, want length 4", got)
+	}
+	for _, seq := range [][]kind{a, b} {
+		i := 0
+		for _, k := range seq {
+			if i < len(got) && got[i] == k {
+				i++
+			}
+		}
+		if i != len(got) {
+			t.Errorf("LCS = %v is not a subsequence of %v", got, seq)
+		}
+	}
+}
+
+func TestSequenceBKTree(t *testing.T) {
+	sentences := []string{
+		"connection refused by host",
+		"connection reset by peer",
+		"connection refused by peer",
+		"disk quota exceeded",
+		"disk full",
+		"connection refused by host",
+	}
+
+	tree := NewSequenceBKTree(Levenshtein[string])
+	for _, s := range sentences {
+		tree.Add(strings.Fields(s))
+	}
+
+	if tree.Size() != 5 {
+		t.Errorf("Size() = %d, want 5", tree.Size())
+	}
+
+	results := tree.Search(strings.Fields("connection refused by peer"), 1)
+	var got []string
+	for _, r := range results {
+		got = append(got, strings.Join(r.Sequence, " "))
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"connection refused by host",
+		"connection refused by peer",
+		"connection reset by peer",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Search = %v, want %v", got, want)
+	}
+}
+
+func TestSequenceBKTreeMatchesBKTree(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tree := NewBKTree()
+	seqTree := NewSequenceBKTree(Levenshtein[byte])
+	for i := 0; i < 200; i++ {
+		word := randomString(rng, 1+rng.Intn(8), "abcd")
+		tree.Add(word)
+		seqTree.Add([]byte(word))
+	}
+	if tree.Size() != seqTree.Size() {
+		t.Fatalf("Size() = %d and %d", tree.Size(), seqTree.Size())
+	}
+
+	for iter := 0; iter < 50; iter++ {
+		query := randomString(rng, 1+rng.Intn(8), "abcd")
+		want := tree.SearchWithScores(query, 2)
+		got := seqTree.Search([]byte(query), 2)
+		if len(got) != len(want) {
+			t.Fatalf("Search(%q) found %d sequences, want %d", query, len(got), len(want))
+		}
+		for i := range got {
+			if string(got[i].Sequence) != want[i].Word || got[i].Distance != want[i].Distance || got[i].Score != want[i].Score {
+				t.Fatalf("Search(%q)[%d] = %+v, want %+v", query, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func BenchmarkWordDistance(b *testing.B) {
+	s1 := "The quick brown fox jumps over the lazy dog"
+	s2 := "The quick brown fox jumped over the lazy dogs"
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		WordDistance(s1, s2)
+	}
+}
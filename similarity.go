@@ -0,0 +1,91 @@
+package fuzzy
+
+// Similarity scores how alike two strings are, from 0 for nothing in common
+// to 1 for identical strings. Every similarity in this package follows these
+// semantics, so scores from different metrics and indexes can be mixed and
+// filtered with a single threshold.
+type Similarity func(s1, s2 string) float64
+
+// DistanceSimilarity turns a distance into a Similarity by dividing it by
+// bound, the largest distance possible between the two strings. Two empty
+// strings are identical and score 1.
+func DistanceSimilarity(dist DistanceFunc, bound func(s1, s2 string) int) Similarity {
+	return func(s1, s2 string) float64 {
+		return normalizeDistance(dist(s1, s2), bound(s1, s2))
+	}
+}
+
+// MaxLengthBound is the largest Levenshtein or Damerau-Levenshtein distance
+// between two strings: the byte length of the longer one
+func MaxLengthBound(s1, s2 string) int {
+	return maxInt(len(s1), len(s2))
+}
+
+// SumLengthBound is the largest insertion/deletion distance between two
+// strings, as computed by MyersDistance: the sum of their byte lengths
+func SumLengthBound(s1, s2 string) int {
+	return len(s1) + len(s2)
+}
+
+func normalizeDistance(dist, bound int) float64 {
+	if bound <= 0 {
+		return 1
+	}
+	score := 1 - float64(dist)/float64(bound)
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// LevenshteinSimilarity is 1 - LevenshteinDistance / MaxLengthBound
+func LevenshteinSimilarity(s1, s2 string) float64 {
+	return normalizeDistance(LevenshteinDistance(s1, s2), MaxLengthBound(s1, s2))
+}
+
+// DamerauLevenshteinSimilarity is 1 - UnrestrictedDamerauLevenshteinDistance
+// / MaxLengthBound
+func DamerauLevenshteinSimilarity(s1, s2 string) float64 {
+	return normalizeDistance(UnrestrictedDamerauLevenshteinDistance(s1, s2), MaxLengthBound(s1, s2))
+}
+
+// OSASimilarity is 1 - OSADistance / MaxLengthBound
+func OSASimilarity(s1, s2 string) float64 {
+	return normalizeDistance(OSADistance(s1, s2), MaxLengthBound(s1, s2))
+}
+
+// IndelSimilarity is 1 - MyersDistance / SumLengthBound, which equals
+// 2*LCSLength / (len(s1)+len(s2))
+func IndelSimilarity(s1, s2 string) float64 {
+	return normalizeDistance(MyersDistance(s1, s2), SumLengthBound(s1, s2))
+}
+
+// QGramSimilarity returns a Similarity comparing q-gram profiles, defined as
+// 1 - QGram.Distance divided by the total number of q-grams in both strings.
+// Strings too short to hold a q-gram only match when equal.
+func QGramSimilarity(q int) Similarity {
+	return func(s1, s2 string) float64 {
+		qg1 := NewQGram(s1, q)
+		qg2 := NewQGram(s2, q)
+		if len(qg1.grams) == 0 && len(qg2.grams) == 0 && s1 != s2 {
+			return 0
+		}
+		return qg1.Similarity(qg2)
+	}
+}
+
+// QGramCosineSimilarity returns a Similarity computing the cosine similarity
+// of q-gram profiles. Strings too short to hold a q-gram only match when equal.
+func QGramCosineSimilarity(q int) Similarity {
+	return func(s1, s2 string) float64 {
+		qg1 := NewQGram(s1, q)
+		qg2 := NewQGram(s2, q)
+		if len(qg1.grams) == 0 || len(qg2.grams) == 0 {
+			if s1 == s2 {
+				return 1
+			}
+			return 0
+		}
+		return qg1.CosineSimilarity(qg2)
+	}
+}
@@ -0,0 +1,98 @@
+package fuzzy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSimilarityRange(t *testing.T) {
+	similarities := map[string]Similarity{
+		"levenshtein":   LevenshteinSimilarity,
+		"damerau":       DamerauLevenshteinSimilarity,
+		"osa":           OSASimilarity,
+		"indel":         IndelSimilarity,
+		"qgram":         QGramSimilarity(2),
+		"qgram-cosine":  QGramCosineSimilarity(2),
+		"bounded-myers": DistanceSimilarity(MyersDistance, SumLengthBound),
+	}
+
+	pairs := [][2]string{
+		{"", ""},
+		{"a", ""},
+		{"a", "b"},
+		{"kitten", "sitting"},
+		{"abc", "xyz"},
+		{"hello world", "world hello"},
+	}
+
+	for name, sim := range similarities {
+		for _, p := range pairs {
+			score := sim(p[0], p[1])
+			if score < 0 || score > 1 {
+				t.Errorf("%s(%q, %q) = %f, outside [0, 1]", name, p[0], p[1], score)
+			}
+			if math.Abs(score-sim(p[1], p[0])) > 1e-9 {
+				t.Errorf("%s is not symmetric on %q, %q", name, p[0], p[1])
+			}
+		}
+
+		if got := sim("same", "same"); math.Abs(got-1) > 1e-9 {
+			t.Errorf("%s of identical strings = %f, want 1", name, got)
+		}
+		if got := sim("abc", "xyz"); got != 0 {
+			t.Errorf("%s of disjoint strings = %f, want 0", name, got)
+		}
+	}
+}
+
+func TestSimilarityValues(t *testing.T) {
+	tests := []struct {
+		name string
+		sim  Similarity
+		s1   string
+		s2   string
+		want float64
+	}{
+		{"levenshtein", LevenshteinSimilarity, "kitten", "sitting", 1 - 3.0/7},
+		{"damerau", DamerauLevenshteinSimilarity, "ca", "abc", 1 - 2.0/3},
+		{"osa", OSASimilarity, "ca", "abc", 0},
+		{"indel", IndelSimilarity, "abcd", "abdc", 2 * 3.0 / 8},
+		{"qgram", QGramSimilarity(2), "abcd", "abce", 1 - 2.0/6},
+	}
+
+	for _, tt := range tests {
+		got := tt.sim(tt.s1, tt.s2)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s(%q, %q) = %f, want %f", tt.name, tt.s1, tt.s2, got, tt.want)
+		}
+	}
+}
+
+func TestSearchResultScores(t *testing.T) {
+	tree := NewBKTree()
+	for _, word := range []string{"book", "books", "cook"} {
+		tree.Add(word)
+	}
+
+	for _, r := range tree.SearchWithScores("book", 1) {
+		if want := LevenshteinSimilarity("book", r.Word); r.Score != want {
+			t.Errorf("BKTree score for %q = %f, want %f", r.Word, r.Score, want)
+		}
+	}
+
+	ti := NewTrigramIndex()
+	ti.Add("hello world", 0)
+	for _, r := range ti.SearchWithDistance("hello wrld", 2) {
+		if want := LevenshteinSimilarity("hello wrld", r.Text); r.Score != want {
+			t.Errorf("TrigramIndex score for %q = %f, want %f", r.Text, r.Score, want)
+		}
+	}
+
+	ng := NewNGram(2)
+	ng.Add("aaaa", 0)
+	for _, r := range ng.Search("aa", 0) {
+		if r.Score > 1 {
+			t.Errorf("NGram score for %q = %f, want at most 1", r.Text, r.Score)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package fuzzy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// SubstitutionMatrix scores aligning one byte against another, such as the
+// BLOSUM and PAM matrices used for amino acid sequences
+type SubstitutionMatrix struct {
+	index    [256]int // Row of each byte in scores, -1 when not in the alphabet
+	scores   [][]int
+	fallback int // Score for pairs involving a byte outside the alphabet
+
+	// Matrices from MatchMismatchMatrix cover every byte without a table
+	identity bool
+	match    int
+	mismatch int
+}
+
+// BLOSUM62 is the BLOSUM62 amino acid substitution matrix
+var BLOSUM62 = mustParseSubstitutionMatrix(blosum62)
+
+// PAM250 is the PAM250 amino acid substitution matrix
+var PAM250 = mustParseSubstitutionMatrix(pam250)
+
+// NewSubstitutionMatrix creates a matrix over the bytes of alphabet, where
+// scores[i][j] is the score of aligning alphabet[i] with alphabet[j]. Lower
+// case letters share the scores of their upper case form unless the alphabet
+// lists them separately. Pairs involving bytes outside the alphabet score
+// as the '*' row if the alphabet has one, and as the lowest score otherwise.
+func NewSubstitutionMatrix(alphabet string, scores [][]int) (*SubstitutionMatrix, error) {
+	if len(scores) != len(alphabet) {
+		return nil, fmt.Errorf("substitution matrix has %d rows for %d symbols", len(scores), len(alphabet))
+	}
+
+	sm := &SubstitutionMatrix{scores: scores}
+	for i := range sm.index {
+		sm.index[i] = -1
+	}
+
+	lowest := 0
+	for i := 0; i < len(alphabet); i++ {
+		if len(scores[i]) != len(alphabet) {
+			return nil, fmt.Errorf("substitution matrix row %q has %d columns for %d symbols",
+				alphabet[i], len(scores[i]), len(alphabet))
+		}
+		if sm.index[alphabet[i]] >= 0 {
+			return nil, fmt.Errorf("substitution matrix symbol %q appears twice", alphabet[i])
+		}
+		sm.index[alphabet[i]] = i
+		for _, score := range scores[i] {
+			if score < lowest {
+				lowest = score
+			}
+		}
+	}
+
+	for c := 'a'; c <= 'z'; c++ {
+		if sm.index[c] < 0 {
+			sm.index[c] = sm.index[c-'a'+'A']
+		}
+	}
+
+	sm.fallback = lowest
+	if star := sm.index['*']; star >= 0 {
+		sm.fallback = scores[star][0]
+		for _, score := range scores[star] {
+			if score < sm.fallback {
+				sm.fallback = score
+			}
+		}
+	}
+
+	return sm, nil
+}
+
+// MatchMismatchMatrix creates a matrix over all bytes that scores equal
+// bytes with match and different bytes with mismatch
+func MatchMismatchMatrix(match, mismatch int) *SubstitutionMatrix {
+	return &SubstitutionMatrix{
+		identity: true,
+		match:    match,
+		mismatch: mismatch,
+	}
+}
+
+// ParseSubstitutionMatrix reads a matrix in the NCBI text format: lines
+// starting with '#' are comments, the first other line lists the column
+// symbols and every following line holds a row symbol and its scores.
+func ParseSubstitutionMatrix(r io.Reader) (*SubstitutionMatrix, error) {
+	scanner := bufio.NewScanner(r)
+
+	var alphabet []byte
+	var rows [][]int
+	var rowSymbols []byte
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if alphabet == nil {
+			for _, f := range fields {
+				if len(f) != 1 {
+					return nil, fmt.Errorf("invalid substitution matrix symbol %q", f)
+				}
+				alphabet = append(alphabet, f[0])
+			}
+			continue
+		}
+
+		if len(fields[0]) != 1 || len(fields) != len(alphabet)+1 {
+			return nil, fmt.Errorf("invalid substitution matrix row %q", line)
+		}
+
+		row := make([]int, len(alphabet))
+		for i, f := range fields[1:] {
+			score, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid substitution matrix score %q: %w", f, err)
+			}
+			row[i] = score
+		}
+		rowSymbols = append(rowSymbols, fields[0][0])
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if alphabet == nil {
+		return nil, errors.New("empty substitution matrix")
+	}
+	if string(rowSymbols) != string(alphabet) {
+		return nil, fmt.Errorf("substitution matrix rows %q do not match columns %q", rowSymbols, alphabet)
+	}
+
+	return NewSubstitutionMatrix(string(alphabet), rows)
+}
+
+func mustParseSubstitutionMatrix(text string) *SubstitutionMatrix {
+	sm, err := ParseSubstitutionMatrix(strings.NewReader(text))
+	if err != nil {
+		panic(err)
+	}
+	return sm
+}
+
+// Score returns the score of aligning a with b
+func (sm *SubstitutionMatrix) Score(a, b byte) int {
+	if sm.identity {
+		if a == b {
+			return sm.match
+		}
+		return sm.mismatch
+	}
+
+	i, j := sm.index[a], sm.index[b]
+	if i < 0 || j < 0 {
+		return sm.fallback
+	}
+	return sm.scores[i][j]
+}
+
+const blosum62 = `
+#  BLOSUM62, from the NCBI distribution
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  4 -1 -2 -2  0 -1 -1  0 -2 -1 -1 -1 -1 -2 -1  1  0 -3 -2  0 -2 -1  0 -4
+R -1  5  0 -2 -3  1  0 -2  0 -3 -2  2 -1 -3 -2 -1 -1 -3 -2 -3 -1  0 -1 -4
+N -2  0  6  1 -3  0  0  0  1 -3 -3  0 -2 -3 -2  1  0 -4 -2 -3  3  0 -1 -4
+D -2 -2  1  6 -3  0  2 -1 -1 -3 -4 -1 -3 -3 -1  0 -1 -4 -3 -3  4  1 -1 -4
+C  0 -3 -3 -3  9 -3 -4 -3 -3 -1 -1 -3 -1 -2 -3 -1 -1 -2 -2 -1 -3 -3 -2 -4
+Q -1  1  0  0 -3  5  2 -2  0 -3 -2  1  0 -3 -1  0 -1 -2 -1 -2  0  3 -1 -4
+E -1  0  0  2 -4  2  5 -2  0 -3 -3  1 -2 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+G  0 -2  0 -1 -3 -2 -2  6 -2 -4 -4 -2 -3 -3 -2  0 -2 -2 -3 -3 -1 -2 -1 -4
+H -2  0  1 -1 -3  0  0 -2  8 -3 -3 -1 -2 -1 -2 -1 -2 -2  2 -3  0  0 -1 -4
+I -1 -3 -3 -3 -1 -3 -3 -4 -3  4  2 -3  1  0 -3 -2 -1 -3 -1  3 -3 -3 -1 -4
+L -1 -2 -3 -4 -1 -2 -3 -4 -3  2  4 -2  2  0 -3 -2 -1 -2 -1  1 -4 -3 -1 -4
+K -1  2  0 -1 -3  1  1 -2 -1 -3 -2  5 -1 -3 -1  0 -1 -3 -2 -2  0  1 -1 -4
+M -1 -1 -2 -3 -1  0 -2 -3 -2  1  2 -1  5  0 -2 -1 -1 -1 -1  1 -3 -1 -1 -4
+F -2 -3 -3 -3 -2 -3 -3 -3 -1  0  0 -3  0  6 -4 -2 -2  1  3 -1 -3 -3 -1 -4
+P -1 -2 -2 -1 -3 -1 -1 -2 -2 -3 -3 -1 -2 -4  7 -1 -1 -4 -3 -2 -2 -1 -2 -4
+S  1 -1  1  0 -1  0  0  0 -1 -2 -2  0 -1 -2 -1  4  1 -3 -2 -2  0  0  0 -4
+T  0 -1  0 -1 -1 -1 -1 -2 -2 -1 -1 -1 -1 -2 -1  1  5 -2 -2  0 -1 -1  0 -4
+W -3 -3 -4 -4 -2 -2 -3 -2 -2 -3 -2 -3 -1  1 -4 -3 -2 11  2 -3 -4 -3 -2 -4
+Y -2 -2 -2 -3 -2 -1 -2 -3  2 -1 -1 -2 -1  3 -3 -2 -2  2  7 -1 -3 -2 -1 -4
+V  0 -3 -3 -3 -1 -2 -2 -3 -3  3  1 -2  1 -1 -2 -2  0 -3 -1  4 -3 -2 -1 -4
+B -2 -1  3  4 -3  0  1 -1  0 -3 -4  0 -3 -3 -2  0 -1 -4 -3 -3  4  1 -1 -4
+Z -1  0  0  1 -3  3  4 -2  0 -3 -3  1 -1 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+X  0 -1 -1 -1 -2 -1 -1 -1 -1 -1 -1 -1 -1 -1 -2  0  0 -2 -1 -1 -1 -1 -1 -4
+* -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4  1
+`
+
+const pam250 = `
+#  PAM250, from the NCBI distribution
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  2 -2  0  0 -2  0  0  1 -1 -1 -2 -1 -1 -3  1  1  1 -6 -3  0  0  0  0 -8
+R -2  6  0 -1 -4  1 -1 -3  2 -2 -3  3  0 -4  0  0 -1  2 -4 -2 -1  0 -1 -8
+N  0  0  2  2 -4  1  1  0  2 -2 -3  1 -2 -3  0  1  0 -4 -2 -2  2  1  0 -8
+D  0 -1  2  4 -5  2  3  1  1 -2 -4  0 -3 -6 -1  0  0 -7 -4 -2  3  3 -1 -8
+C -2 -4 -4 -5 12 -5 -5 -3 -3 -2 -6 -5 -5 -4 -3  0 -2 -8  0 -2 -4 -5 -3 -8
+Q  0  1  1  2 -5  4  2 -1  3 -2 -2  1 -1 -5  0 -1 -1 -5 -4 -2  1  3 -1 -8
+E  0 -1  1  3 -5  2  4  0  1 -2 -3  0 -2 -5 -1  0  0 -7 -4 -2  3  3 -1 -8
+G  1 -3  0  1 -3 -1  0  5 -2 -3 -4 -2 -3 -5  0  1  0 -7 -5 -1  0  0 -1 -8
+H -1  2  2  1 -3  3  1 -2  6 -2 -2  0 -2 -2  0 -1 -1 -3  0 -2  1  2 -1 -8
+I -1 -2 -2 -2 -2 -2 -2 -3 -2  5  2 -2  2  1 -2 -1  0 -5 -1  4 -2 -2 -1 -8
+L -2 -3 -3 -4 -6 -2 -3 -4 -2  2  6 -3  4  2 -3 -3 -2 -2 -1  2 -3 -3 -1 -8
+K -1  3  1  0 -5  1  0 -2  0 -2 -3  5  0 -5 -1  0  0 -3 -4 -2  1  0 -1 -8
+M -1  0 -2 -3 -5 -1 -2 -3 -2  2  4  0  6  0 -2 -2 -1 -4 -2  2 -2 -2 -1 -8
+F -3 -4 -3 -6 -4 -5 -5 -5 -2  1  2 -5  0  9 -5 -3 -3  0  7 -1 -4 -5 -2 -8
+P  1  0  0 -1 -3  0 -1  0  0 -2 -3 -1 -2 -5  6  1  0 -6 -5 -1 -1  0 -1 -8
+S  1  0  1  0  0 -1  0  1 -1 -1 -3  0 -2 -3  1  2  1 -2 -3 -1  0  0  0 -8
+T  1 -1  0  0 -2 -1  0  0 -1  0 -2  0 -1 -3  0  1  3 -5 -3  0  0 -1  0 -8
+W -6  2 -4 -7 -8 -5 -7 -7 -3 -5 -2 -3 -4  0 -6 -2 -5 17  0 -6 -5 -6 -4 -8
+Y -3 -4 -2 -4  0 -4 -4 -5  0 -1 -1 -4 -2  7 -5 -3 -3  0 10 -2 -3 -4 -2 -8
+V  0 -2 -2 -2 -2 -2 -2 -1 -2  4  2 -2  2 -1 -1 -1  0 -6 -2  4 -2 -2 -1 -8
+B  0 -1  2  3 -4  1  3  0  1 -2 -3  1 -2 -4 -1  0  0 -5 -3 -2  3  2 -1 -8
+Z  0  0  1  3 -5  3  3  0  2 -2 -3  0 -2 -5  0  0 -1 -6 -4 -2  2  3 -1 -8
+X  0 -1  0 -1 -3 -1 -1 -1 -1 -1 -1 -1 -1 -2 -1  0  0 -4 -2 -1 -1 -1 -1 -8
+* -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8 -8  1
+`
@@ -0,0 +1,87 @@
+package fuzzy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinSubstitutionMatrices(t *testing.T) {
+	const alphabet = "ARNDCQEGHILKMFPSTWYVBZX*"
+
+	matrices := map[string]*SubstitutionMatrix{"BLOSUM62": BLOSUM62, "PAM250": PAM250}
+	for name, matrix := range matrices {
+		for i := 0; i < len(alphabet); i++ {
+			for j := 0; j < len(alphabet); j++ {
+				a, b := alphabet[i], alphabet[j]
+				if matrix.Score(a, b) != matrix.Score(b, a) {
+					t.Errorf("%s is not symmetric at %c/%c", name, a, b)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		matrix *SubstitutionMatrix
+		a, b   byte
+		want   int
+	}{
+		{BLOSUM62, 'W', 'W', 11},
+		{BLOSUM62, 'C', 'C', 9},
+		{BLOSUM62, 'A', 'R', -1},
+		{BLOSUM62, 'a', 'r', -1},
+		{BLOSUM62, 'A', '#', -4},
+		{PAM250, 'W', 'W', 17},
+		{PAM250, 'C', 'C', 12},
+		{PAM250, 'W', 'C', -8},
+	}
+
+	for _, tt := range tests {
+		got := tt.matrix.Score(tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf("Score(%c, %c) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseSubstitutionMatrix(t *testing.T) {
+	text := `
+# Transliteration-friendly matrix
+   a  e  o
+a  3  1 -1
+e  1  3  0
+o -1  0  3
+`
+	matrix, err := ParseSubstitutionMatrix(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := matrix.Score('a', 'e'); got != 1 {
+		t.Errorf("Score(a, e) = %d, want 1", got)
+	}
+	if got := matrix.Score('a', 'z'); got != -1 {
+		t.Errorf("Score(a, z) = %d, want the lowest score -1", got)
+	}
+
+	invalid := []string{
+		"",
+		"   a  b\na  1",
+		"   a  b\na  1  0\nc  0  1",
+		"   a  b\na  1  x\nb  0  1",
+	}
+	for _, text := range invalid {
+		if _, err := ParseSubstitutionMatrix(strings.NewReader(text)); err == nil {
+			t.Errorf("ParseSubstitutionMatrix(%q) succeeded, want error", text)
+		}
+	}
+}
+
+func TestMatchMismatchMatrix(t *testing.T) {
+	matrix := MatchMismatchMatrix(5, -4)
+	if got := matrix.Score(0xff, 0xff); got != 5 {
+		t.Errorf("Score of equal bytes = %d, want 5", got)
+	}
+	if got := matrix.Score('a', 'b'); got != -4 {
+		t.Errorf("Score of different bytes = %d, want -4", got)
+	}
+}
@@ -1,13 +1,21 @@
 package fuzzy
 
 import (
+	"container/heap"
 	"sort"
-	"strings"
+	"sync"
 )
 
 type SuffixArray struct {
 	text     string
 	suffixes []int
+
+	// lcp is built on first use by LCP
+	lcp     []int
+	lcpOnce sync.Once
+
+	// mapping backs text and suffixes when loaded by OpenSuffixArray
+	mapping *mapping
 }
 
 func NewSuffixArray(text string) *SuffixArray {
@@ -16,234 +24,486 @@ func NewSuffixArray(text string) *SuffixArray {
 	return sa
 }
 
+// build sorts the suffixes in linear time with SA-IS
 func (sa *SuffixArray) build() {
-	n := len(sa.text)
-	sa.suffixes = make([]int, n)
-	
-	for i := 0; i < n; i++ {
-		sa.suffixes[i] = i
-	}
-	
-	sort.Slice(sa.suffixes, func(i, j int) bool {
-		return sa.text[sa.suffixes[i]:] < sa.text[sa.suffixes[j]:]
-	})
+	sa.suffixes = sais([]byte(sa.text), 255)
 }
 
+// Search returns the positions at which pattern occurs, in suffix order.
+// Both ends of the range of matching suffixes are found by binary search.
 func (sa *SuffixArray) Search(pattern string) []int {
-	n := len(sa.suffixes)
-	left := sort.Search(n, func(i int) bool {
-		suffix := sa.text[sa.suffixes[i]:]
-		return strings.HasPrefix(suffix, pattern) || suffix >= pattern
-	})
-	
-	if left >= n || !strings.HasPrefix(sa.text[sa.suffixes[left]:], pattern) {
+	lo, hi := sa.prefixRange(pattern)
+	if lo == hi {
 		return nil
 	}
 	
-	right := left + 1
-	for right < n && strings.HasPrefix(sa.text[sa.suffixes[right]:], pattern) {
-		right++
+	results := make([]int, hi-lo)
+	copy(results, sa.suffixes[lo:hi])
+	return results
+}
+
+// Count returns the number of possibly overlapping occurrences of pattern
+func (sa *SuffixArray) Count(pattern string) int {
+	lo, hi := sa.prefixRange(pattern)
+	return hi - lo
+}
+
+// SearchOptions controls the order and paging of SearchWithOptions
+type SearchOptions struct {
+	// Sorted orders positions by text offset instead of suffix order
+	Sorted bool
+	
+	// Offset skips that many positions, and a positive Limit returns at
+	// most that many, so frequent patterns can be read page by page
+	Offset int
+	Limit  int
+}
+
+// SearchWithOptions returns the positions at which pattern occurs, ordered
+// and paged as selected by opts. In suffix order a page costs O(Limit) after
+// the binary search. Sorted pages select the Offset+Limit smallest positions
+// of the occ occurrences in O(occ log(Offset+Limit)) time and
+// O(Offset+Limit) memory; without a Limit every occurrence is sorted.
+func (sa *SuffixArray) SearchWithOptions(pattern string, opts SearchOptions) []int {
+	lo, hi := sa.prefixRange(pattern)
+	positions := sa.suffixes[lo:hi]
+	offset := maxInt(opts.Offset, 0)
+	if opts.Sorted {
+		if opts.Limit > 0 && offset < len(positions) && opts.Limit < len(positions)-offset {
+			positions = smallestPositions(positions, offset+opts.Limit)
+		} else {
+			positions = append([]int(nil), positions...)
+			sort.Ints(positions)
+		}
 	}
 	
-	results := make([]int, right-left)
-	for i := left; i < right; i++ {
-		results[i-left] = sa.suffixes[i]
+	start := min(offset, len(positions))
+	end := len(positions)
+	if opts.Limit > 0 && opts.Limit < end-start {
+		end = start + opts.Limit
+	}
+	if start == end {
+		return nil
 	}
 	
+	results := make([]int, end-start)
+	copy(results, positions[start:end])
 	return results
 }
 
-func (sa *SuffixArray) FuzzySearch(pattern string, maxErrors int) []int {
-	var results []int
-	seen := make(map[int]bool)
+// smallestPositions returns the k smallest positions in ascending order. It
+// keeps the best k seen in a max-heap, replacing the top when a smaller
+// position comes along.
+func smallestPositions(positions []int, k int) []int {
+	h := &maxHeap{IntSlice: append(sort.IntSlice(nil), positions[:k]...)}
+	heap.Init(h)
+	for _, p := range positions[k:] {
+		if p < h.IntSlice[0] {
+			h.IntSlice[0] = p
+			heap.Fix(h, 0)
+		}
+	}
+	sort.Ints(h.IntSlice)
+	return h.IntSlice
+}
+
+// maxHeap is a heap of ints with the largest on top
+type maxHeap struct{ sort.IntSlice }
+
+func (h maxHeap) Less(i, j int) bool { return h.IntSlice[i] > h.IntSlice[j] }
+func (h *maxHeap) Push(x any)        { h.IntSlice = append(h.IntSlice, x.(int)) }
+func (h *maxHeap) Pop() any {
+	last := h.IntSlice[len(h.IntSlice)-1]
+	h.IntSlice = h.IntSlice[:len(h.IntSlice)-1]
+	return last
+}
+
+// prefixRange returns the range [lo, hi) of suffix array entries whose
+// suffixes start with prefix
+func (sa *SuffixArray) prefixRange(prefix string) (int, int) {
+	n := len(sa.suffixes)
+	m := len(prefix)
+	truncated := func(i int) string {
+		s := sa.suffixes[i]
+		if s+m > len(sa.text) {
+			return sa.text[s:]
+		}
+		return sa.text[s : s+m]
+	}
+
+	lo := sort.Search(n, func(i int) bool {
+		return truncated(i) >= prefix
+	})
+	hi := lo + sort.Search(n-lo, func(i int) bool {
+		return truncated(lo+i) > prefix
+	})
+	return lo, hi
+}
+
+// narrow restricts the range [lo, hi) of suffixes sharing a prefix of length
+// depth to those followed by c
+func (sa *SuffixArray) narrow(lo, hi, depth int, c byte) (int, int) {
+	// Suffixes of exactly depth bytes sort first and never match
+	next := func(i int) int {
+		s := sa.suffixes[i] + depth
+		if s >= len(sa.text) {
+			return -1
+		}
+		return int(sa.text[s])
+	}
+
+	start := lo + sort.Search(hi-lo, func(i int) bool {
+		return next(lo+i) >= int(c)
+	})
+	end := start + sort.Search(hi-start, func(i int) bool {
+		return next(start+i) > int(c)
+	})
+	return start, end
+}
+
+// FuzzySearch finds the positions where a substring within maxErrors edits
+// of pattern starts. Each position is reported once with its smallest
+// distance and the longest substring reaching it, ordered by Start.
+//
+// By the pigeonhole principle, a match with at most maxErrors edits contains
+// one of maxErrors+1 pattern pieces unchanged. Each piece is located with a
+// binary search, and only starts near its occurrences are verified with a
+// dynamic programming band of width 2*maxErrors+1.
+func (sa *SuffixArray) FuzzySearch(pattern string, maxErrors int) []Match {
+	m := len(pattern)
+	n := len(sa.text)
+	if m == 0 || maxErrors < 0 {
+		return nil
+	}
 	
-	for _, suffix := range sa.suffixes {
-		remaining := sa.text[suffix:]
-		if len(remaining) < len(pattern)-maxErrors {
-			continue
+	var candidates []int
+	if m <= maxErrors {
+		// Deleting the whole pattern is within budget, so every start matches
+		candidates = make([]int, n)
+		for i := range candidates {
+			candidates[i] = i
 		}
-		
-		dist := approximateMatch(pattern, remaining, maxErrors)
-		if dist <= maxErrors {
-			if !seen[suffix] {
-				results = append(results, suffix)
-				seen[suffix] = true
+	} else {
+		seen := make(map[int]struct{})
+		pieces := maxErrors + 1
+		for p := 0; p < pieces; p++ {
+			from, to := p*m/pieces, (p+1)*m/pieces
+			lo, hi := sa.prefixRange(pattern[from:to])
+			for i := lo; i < hi; i++ {
+				start := sa.suffixes[i] - from
+				for s := maxInt(start-maxErrors, 0); s <= start+maxErrors && s < n; s++ {
+					seen[s] = struct{}{}
+				}
 			}
 		}
+		
+		candidates = make([]int, 0, len(seen))
+		for s := range seen {
+			candidates = append(candidates, s)
+		}
+		sort.Ints(candidates)
+	}
+	
+	var results []Match
+	band := newBandedMatcher(pattern, maxErrors)
+	for _, start := range candidates {
+		if length, dist := band.match(sa.text[start:]); dist <= maxErrors {
+			results = append(results, Match{Start: start, End: start + length, Distance: dist})
+		}
 	}
 	
 	return results
 }
 
-func approximateMatch(pattern, text string, maxErrors int) int {
-	m := len(pattern)
-	n := len(text)
-	
-	if m == 0 {
-		return 0
+// bandedMatcher computes the edit distance between a pattern and the best
+// prefix of a text, only filling the cells within k of the diagonal since
+// any other cell exceeds k
+type bandedMatcher struct {
+	pattern    string
+	k          int
+	prev, curr []int
+}
+
+func newBandedMatcher(pattern string, k int) *bandedMatcher {
+	return &bandedMatcher{
+		pattern: pattern,
+		k:       k,
+		prev:    make([]int, 2*k+2),
+		curr:    make([]int, 2*k+2),
 	}
+}
+
+// match returns the length of the longest prefix of text at the smallest
+// edit distance from the pattern, and that distance, which is k+1 when it
+// exceeds k. Cell (i, j) of the table is stored at index j-i+k of row i.
+func (b *bandedMatcher) match(text string) (int, int) {
+	k, m := b.k, len(b.pattern)
+	inf := k + 1
+	width := 2*k + 1
+	prev, curr := b.prev, b.curr
 	
-	prev := make([]int, m+1)
-	curr := make([]int, m+1)
-	
-	for i := 0; i <= m; i++ {
-		prev[i] = i
+	for idx := 0; idx <= width; idx++ {
+		prev[idx] = inf
+	}
+	for j := 0; j <= k && j <= len(text); j++ {
+		prev[j+k] = j
 	}
 	
-	for j := 1; j <= n; j++ {
-		curr[0] = 0
-		minDist := m
-		
-		for i := 1; i <= m; i++ {
-			cost := 0
-			if pattern[i-1] != text[j-1] {
-				cost = 1
+	for i := 1; i <= m; i++ {
+		rowMin := inf
+		for idx := 0; idx < width; idx++ {
+			j := i + idx - k
+			curr[idx] = inf
+			if j < 0 || j > len(text) {
+				continue
 			}
 			
-			curr[i] = min(min(prev[i]+1, curr[i-1]+1), prev[i-1]+cost)
-			
-			if curr[i] < minDist {
-				minDist = curr[i]
+			v := inf
+			if j == 0 {
+				v = i
+			} else {
+				cost := 1
+				if b.pattern[i-1] == text[j-1] {
+					cost = 0
+				}
+				v = prev[idx] + cost // substitution
+				if prev[idx+1]+1 < v {
+					v = prev[idx+1] + 1 // deletion from the pattern
+				}
+				if idx > 0 && curr[idx-1]+1 < v {
+					v = curr[idx-1] + 1 // insertion into the pattern
+				}
 			}
+			
+			curr[idx] = min(v, inf)
+			rowMin = min(rowMin, curr[idx])
 		}
+		curr[width] = inf
 		
-		if minDist > maxErrors {
-			return maxErrors + 1
+		if rowMin > k {
+			return 0, inf
 		}
-		
 		prev, curr = curr, prev
 	}
 	
-	minDist := m
-	for i := 0; i <= m; i++ {
-		if prev[i] < minDist {
-			minDist = prev[i]
+	bestLen, best := 0, inf
+	for idx := 0; idx < width; idx++ {
+		if prev[idx] <= best {
+			bestLen, best = m+idx-k, prev[idx]
 		}
 	}
-	
-	return minDist
+	return bestLen, best
 }
 
+// FMIndex is a compressed full-text index over the Burrows-Wheeler transform
+// of a text. The transform is stored in a wavelet matrix, taking about 9 bits
+// per text byte, plus one sampled text position every sampleRate rows.
 type FMIndex struct {
-	bwt        string
-	firstOcc   map[rune]int
-	occTable   map[rune][]int
-	suffixArr  []int
+	bwt        *waveletMatrix // BWT of the text and a virtual end sentinel, stored as 0 at row primary
+	primary    int            // BWT row holding the sentinel
+	counts     [257]int       // counts[c] is the first BWT row whose suffix starts with byte c
+	sampled    *rankBitVector // Rows whose text position is a multiple of sampleRate
+	samplePos  []int          // Text positions of the sampled rows, in row order
+	isaSamples []int          // isaSamples[j] is the row of text position j*sampleRate
 	sampleRate int
+	mapping    *mapping       // File backing the index when loaded by OpenFMIndex
 }
 
+// NewFMIndex builds an FM-index of text. Every sampleRate-th text position
+// is stored, so Locate walks back at most sampleRate-1 steps per match and
+// Extract at most sampleRate-1 bytes more than requested. The text itself
+// is not kept.
 func NewFMIndex(text string, sampleRate int) *FMIndex {
+	if sampleRate < 1 {
+		sampleRate = 1
+	}
 	fm := &FMIndex{sampleRate: sampleRate}
 	fm.build(text)
 	return fm
 }
 
+// build derives the BWT from the suffix array of text. Instead of appending
+// a "$" byte that could also occur in the text, the end of the text acts as
+// a virtual sentinel sorting before every byte: row 0 is the empty suffix,
+// and row r+1 holds the suffix at SA rank r.
 func (fm *FMIndex) build(text string) {
-	text = text + "$"
 	n := len(text)
+	rows := n + 1
 	
-	sa := NewSuffixArray(text)
-	fm.suffixArr = make([]int, 0, (n+fm.sampleRate-1)/fm.sampleRate)
+	suffixes := sais([]byte(text), 255)
 	
-	bwtBytes := make([]byte, n)
-	for i, suffix := range sa.suffixes {
+	bwt := make([]byte, rows)
+	fm.sampled = newRankBitVector(rows)
+	fm.isaSamples = make([]int, n/fm.sampleRate+1)
+	
+	if n > 0 {
+		bwt[0] = text[n-1]
+	}
+	if n%fm.sampleRate == 0 {
+		fm.sampled.set(0)
+	}
+	
+	for r, suffix := range suffixes {
+		row := r + 1
 		if suffix == 0 {
-			bwtBytes[i] = text[n-1]
+			fm.primary = row
 		} else {
-			bwtBytes[i] = text[suffix-1]
+			bwt[row] = text[suffix-1]
 		}
 		
-		if i%fm.sampleRate == 0 {
-			fm.suffixArr = append(fm.suffixArr, suffix)
+		if suffix%fm.sampleRate == 0 {
+			fm.sampled.set(row)
+			fm.isaSamples[suffix/fm.sampleRate] = row
 		}
 	}
-	fm.bwt = string(bwtBytes)
-	
-	fm.firstOcc = make(map[rune]int)
-	fm.occTable = make(map[rune][]int)
 	
-	counts := make(map[rune]int)
-	for _, r := range fm.bwt {
-		counts[r]++
+	fm.sampled.index()
+	fm.samplePos = make([]int, 0, fm.sampled.rank1(rows))
+	if n%fm.sampleRate == 0 {
+		fm.samplePos = append(fm.samplePos, n)
 	}
-	
-	chars := make([]rune, 0, len(counts))
-	for r := range counts {
-		chars = append(chars, r)
+	for _, suffix := range suffixes {
+		if suffix%fm.sampleRate == 0 {
+			fm.samplePos = append(fm.samplePos, suffix)
+		}
 	}
-	sort.Slice(chars, func(i, j int) bool {
-		return chars[i] < chars[j]
-	})
 	
-	total := 0
-	for _, r := range chars {
-		fm.firstOcc[r] = total
-		total += counts[r]
-		fm.occTable[r] = make([]int, n+1)
+	// The sentinel occupies row 0, so the rows for each byte start after it
+	var freq [256]int
+	for i := 0; i < n; i++ {
+		freq[text[i]]++
+	}
+	fm.counts[0] = 1
+	for c := 0; c < 256; c++ {
+		fm.counts[c+1] = fm.counts[c] + freq[c]
 	}
 	
-	for r := range fm.occTable {
-		count := 0
-		for i := 0; i < n; i++ {
-			fm.occTable[r][i] = count
-			if rune(fm.bwt[i]) == r {
-				count++
-			}
-		}
-		fm.occTable[r][n] = count
+	fm.bwt = newWaveletMatrix(bwt)
+}
+
+// occ returns the number of times c occurs in BWT rows [0, i), discounting
+// the sentinel stored as 0
+func (fm *FMIndex) occ(c byte, i int) int {
+	count := fm.bwt.rank(c, i)
+	if c == 0 && i > fm.primary {
+		count--
 	}
+	return count
 }
 
+// Count returns the number of possibly overlapping occurrences of pattern,
+// compared byte by byte so any UTF-8 text and pattern are supported
 func (fm *FMIndex) Count(pattern string) int {
+	sp, ep := fm.backwardSearch(pattern)
+	return ep - sp + 1
+}
+
+// backwardSearch returns the range [sp, ep] of BWT rows whose suffixes start
+// with pattern, with ep < sp when there are none. The index is built over
+// bytes, so patterns are matched by their UTF-8 encoding.
+func (fm *FMIndex) backwardSearch(pattern string) (int, int) {
 	if len(pattern) == 0 {
-		return 0
+		return 0, -1
 	}
 	
-	runes := []rune(pattern)
-	m := len(runes)
+	m := len(pattern)
+	c := pattern[m-1]
+	sp := fm.counts[c]
+	ep := fm.counts[int(c)+1] - 1
 	
-	c := runes[m-1]
-	first, ok := fm.firstOcc[c]
-	if !ok {
-		return 0
+	for i := m - 2; i >= 0 && sp <= ep; i-- {
+		c = pattern[i]
+		sp = fm.counts[c] + fm.occ(c, sp)
+		ep = fm.counts[c] + fm.occ(c, ep+1) - 1
 	}
 	
-	occ, ok := fm.occTable[c]
-	if !ok {
-		return 0
+	if sp > ep {
+		return 0, -1
 	}
 	
-	sp := first
-	ep := first + occ[len(fm.bwt)] - 1
+	return sp, ep
+}
+
+// Locate returns the sorted text offsets at which pattern occurs
+func (fm *FMIndex) Locate(pattern string) []int {
+	sp, ep := fm.backwardSearch(pattern)
+	if sp > ep {
+		return nil
+	}
 	
-	for i := m - 2; i >= 0 && sp <= ep; i-- {
-		c = runes[i]
-		first, ok = fm.firstOcc[c]
-		if !ok {
-			return 0
-		}
-		
-		occ, ok = fm.occTable[c]
-		if !ok {
-			return 0
-		}
-		
-		sp = first + occ[sp]
-		ep = first + occ[ep+1] - 1
+	positions := make([]int, 0, ep-sp+1)
+	for i := sp; i <= ep; i++ {
+		positions = append(positions, fm.position(i))
 	}
+	sort.Ints(positions)
 	
-	if sp > ep {
-		return 0
+	return positions
+}
+
+// position recovers the text offset of the suffix at BWT row i by following
+// the LF mapping back to the nearest sampled position. Position 0 is always
+// sampled, so the walk never reaches the sentinel row.
+func (fm *FMIndex) position(i int) int {
+	steps := 0
+	for !fm.sampled.get(i) {
+		c := fm.bwt.access(i)
+		i = fm.counts[c] + fm.occ(c, i)
+		steps++
 	}
+	return fm.samplePos[fm.sampled.rank1(i)] + steps
+}
+
+// Len returns the length in bytes of the indexed text
+func (fm *FMIndex) Len() int {
+	return fm.counts[256] - 1
+}
+
+// Extract reconstructs length bytes of the indexed text starting at offset,
+// truncated at the end of the text. It walks the LF mapping back from the
+// nearest sampled position after the range.
+func (fm *FMIndex) Extract(offset, length int) string {
+	n := fm.Len()
+	if offset < 0 || offset >= n || length <= 0 {
+		return ""
+	}
+	end := min(offset+length, n)
 	
-	return ep - sp + 1
+	// Start from the first sampled position at or after end, or from the
+	// empty suffix at row 0 when none is left before the end of the text
+	pos := (end + fm.sampleRate - 1) / fm.sampleRate * fm.sampleRate
+	row := 0
+	if pos < n {
+		row = fm.isaSamples[pos/fm.sampleRate]
+	} else {
+		pos = n
+	}
+	
+	buf := make([]byte, end-offset)
+	for pos > offset {
+		c := fm.bwt.access(row)
+		pos--
+		if pos < end {
+			buf[pos-offset] = c
+		}
+		row = fm.counts[c] + fm.occ(c, row)
+	}
+	
+	return string(buf)
 }
 
-func (fm *FMIndex) Locate(pattern string) []int {
-	count := fm.Count(pattern)
-	if count == 0 {
-		return nil
+// Snippet returns each occurrence of pattern with up to context bytes of
+// surrounding text on both sides, in text order
+func (fm *FMIndex) Snippet(pattern string, context int) []string {
+	if context < 0 {
+		context = 0
+	}
+	
+	positions := fm.Locate(pattern)
+	snippets := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		start := maxInt(pos-context, 0)
+		snippets = append(snippets, fm.Extract(start, pos+len(pattern)+context-start))
 	}
 	
-	return []int{}
+	return snippets
 }
\ No newline at end of file
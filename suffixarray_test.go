@@ -1,6 +1,10 @@
 package fuzzy
 
 import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -44,8 +48,8 @@ func TestSuffixArrayFuzzySearch(t *testing.T) {
 	
 	results := sa.FuzzySearch("quik", 1)
 	found := false
-	for _, pos := range results {
-		if pos == 4 { // "quick" starts at position 4
+	for _, match := range results {
+		if match.Start == 4 { // "quick" starts at position 4
 			found = true
 			break
 		}
@@ -56,6 +60,24 @@ func TestSuffixArrayFuzzySearch(t *testing.T) {
 	}
 }
 
+func TestSuffixArrayFuzzySearchMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 300; iter++ {
+		text := randomString(rng, rng.Intn(80), "abcd")
+		pattern := randomString(rng, 1+rng.Intn(8), "abcd")
+		k := rng.Intn(4)
+		
+		want := bruteFuzzyLocate(text, pattern, k)
+		got := NewSuffixArray(text).FuzzySearch(pattern, k)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("FuzzySearch(%q, %d) in %q = %v, want %v", pattern, k, text, got, want)
+		}
+	}
+}
+
 func TestFMIndex(t *testing.T) {
 	text := "mississippi"
 	fm := NewFMIndex(text, 2)
@@ -79,6 +101,208 @@ func TestFMIndex(t *testing.T) {
 	}
 }
 
+func TestFMIndexLocate(t *testing.T) {
+	fm := NewFMIndex("mississippi", 3)
+	
+	tests := []struct {
+		pattern string
+		want    []int
+	}{
+		{"ssi", []int{2, 5}},
+		{"i", []int{1, 4, 7, 10}},
+		{"mississippi", []int{0}},
+		{"xyz", nil},
+		{"", nil},
+	}
+	
+	for _, tt := range tests {
+		if got := fm.Locate(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Locate(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSuffixArraySearchWithOptions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		text := randomString(rng, 1+rng.Intn(200), "ab")
+		sa := NewSuffixArray(text)
+		pattern := randomString(rng, 1+rng.Intn(3), "ab")
+		
+		var want []int
+		for i := 0; i+len(pattern) <= len(text); i++ {
+			if text[i:i+len(pattern)] == pattern {
+				want = append(want, i)
+			}
+		}
+		
+		if got := sa.SearchWithOptions(pattern, SearchOptions{Sorted: true}); !reflect.DeepEqual(got, want) {
+			t.Fatalf("sorted Search(%q) in %q = %v, want %v", pattern, text, got, want)
+		}
+		if sa.Count(pattern) != len(want) {
+			t.Fatalf("Count(%q) in %q = %d, want %d", pattern, text, sa.Count(pattern), len(want))
+		}
+		
+		// Pages cover every position once, in the same order as unpaged results
+		for _, sorted := range []bool{false, true} {
+			all := sa.SearchWithOptions(pattern, SearchOptions{Sorted: sorted})
+			limit := 1 + rng.Intn(5)
+			var paged []int
+			for offset := 0; ; offset += limit {
+				page := sa.SearchWithOptions(pattern, SearchOptions{Sorted: sorted, Offset: offset, Limit: limit})
+				if page == nil {
+					break
+				}
+				if len(page) > limit {
+					t.Fatalf("page of %d positions exceeds limit %d", len(page), limit)
+				}
+				paged = append(paged, page...)
+			}
+			if !reflect.DeepEqual(paged, all) {
+				t.Fatalf("pages of %q in %q = %v, want %v", pattern, text, paged, all)
+			}
+		}
+	}
+}
+
+func TestFMIndexLocateMatchesSuffixArray(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 200; iter++ {
+		text := randomString(rng, 1+rng.Intn(200), "abcd")
+		sa := NewSuffixArray(text)
+		fm := NewFMIndex(text, 1+rng.Intn(16))
+		
+		for k := 0; k < 10; k++ {
+			start := rng.Intn(len(text))
+			pattern := text[start : start+1+rng.Intn(min(5, len(text)-start))]
+			
+			want := sa.Search(pattern)
+			sort.Ints(want)
+			got := fm.Locate(pattern)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%q) in %q with sample rate %d = %v, want %v",
+					pattern, text, fm.sampleRate, got, want)
+			}
+			if fm.Count(pattern) != len(want) {
+				t.Fatalf("Count(%q) = %d, want %d", pattern, fm.Count(pattern), len(want))
+			}
+		}
+	}
+}
+
+func TestFMIndexSentinelInText(t *testing.T) {
+	fm := NewFMIndex("cost: $5, tax: $1$", 2)
+	
+	if got := fm.Count("$"); got != 3 {
+		t.Errorf("Count(\"$\") = %d, want 3", got)
+	}
+	if got := fm.Locate("$1$"); !reflect.DeepEqual(got, []int{15}) {
+		t.Errorf("Locate(\"$1$\") = %v, want [15]", got)
+	}
+	
+	empty := NewFMIndex("", 4)
+	if got := empty.Count("a"); got != 0 {
+		t.Errorf("Count on an empty text = %d, want 0", got)
+	}
+}
+
+func TestFMIndexMultilingual(t *testing.T) {
+	corpora := []string{
+		"Übermäßig große Straßen führen über die Brücke. Größe ist relativ.",
+		"Съешь же ещё этих мягких французских булок, да выпей чаю. Ещё!",
+		"Τάχιστη αλώπηξ βαφής ψημένη γη, δρασκελίζει υπέρ νωθρού κυνός.",
+		"いろはにほへと ちりぬるを わかよたれそ つねならむ いろはにほへと",
+		"色は匂へど散りぬるを我が世誰ぞ常ならむ色は匂へど",
+		"naïve café résumé — emoji 👍🏽 and 👍 and 👍🏽 again",
+		"\xff\xfe invalid UTF-8 \xff bytes \xfe\xff",
+	}
+	
+	for _, text := range corpora {
+		sa := NewSuffixArray(text)
+		fm := NewFMIndex(text, 3)
+		
+		// Query every substring starting on a rune boundary, plus a few
+		// patterns absent from all texts
+		var patterns []string
+		for i := range text {
+			for _, l := range []int{1, 2, 3, 4, 6, 9} {
+				if i+l <= len(text) {
+					patterns = append(patterns, text[i:i+l])
+				}
+			}
+		}
+		patterns = append(patterns, "ß", "ё", "λ", "に", "誰", "👍🏽", "\xff", "zzz", "中文")
+		
+		for _, pattern := range patterns {
+			want := sa.Search(pattern)
+			sort.Ints(want)
+			
+			if got := fm.Count(pattern); got != len(want) {
+				t.Fatalf("Count(%q) in %q = %d, want %d", pattern, text, got, len(want))
+			}
+			if got := fm.Locate(pattern); !reflect.DeepEqual(got, want) {
+				t.Fatalf("Locate(%q) in %q = %v, want %v", pattern, text, got, want)
+			}
+		}
+	}
+	
+	fm := NewFMIndex("いろはにほへと いろは", 2)
+	if got := fm.Locate("いろは"); !reflect.DeepEqual(got, []int{0, 22}) {
+		t.Errorf("Locate(\"いろは\") = %v, want [0 22]", got)
+	}
+}
+
+func TestFMIndexExtract(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	texts := []string{"", "a", "mississippi", "Übermäßig große Straßen", randomString(rng, 300, "abcd")}
+	
+	for _, text := range texts {
+		for _, rate := range []int{1, 3, 8, 64} {
+			fm := NewFMIndex(text, rate)
+			if fm.Len() != len(text) {
+				t.Fatalf("Len() = %d, want %d", fm.Len(), len(text))
+			}
+			if got := fm.Extract(0, len(text)); got != text {
+				t.Fatalf("Extract of the whole text = %q, want %q", got, text)
+			}
+			
+			for k := 0; k < 50 && len(text) > 0; k++ {
+				offset := rng.Intn(len(text))
+				length := rng.Intn(20)
+				want := text[offset:min(offset+length, len(text))]
+				if got := fm.Extract(offset, length); got != want {
+					t.Fatalf("Extract(%d, %d) of %q at rate %d = %q, want %q", offset, length, text, rate, got, want)
+				}
+			}
+		}
+	}
+	
+	fm := NewFMIndex("mississippi", 4)
+	for _, tt := range []struct{ offset, length int }{{-1, 3}, {11, 3}, {2, 0}, {2, -1}} {
+		if got := fm.Extract(tt.offset, tt.length); got != "" {
+			t.Errorf("Extract(%d, %d) = %q, want empty", tt.offset, tt.length, got)
+		}
+	}
+}
+
+func TestFMIndexSnippet(t *testing.T) {
+	logs := "12:00 GET /index 200\n12:01 GET /admin 403\n12:02 POST /login 500\n12:03 GET /admin 403\n"
+	fm := NewFMIndex(logs, 8)
+	
+	got := fm.Snippet("403", 11)
+	want := []string{"GET /admin 403\n12:02 POST", "GET /admin 403\n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Snippet(\"403\", 11) = %q, want %q", got, want)
+	}
+	
+	if got := fm.Snippet("12:00", 3); !reflect.DeepEqual(got, []string{"12:00 GE"}) {
+		t.Errorf("Snippet at the start of the text = %q", got)
+	}
+	if got := fm.Snippet("404", 5); len(got) != 0 {
+		t.Errorf("Snippet of a missing pattern = %q, want none", got)
+	}
+}
+
 func BenchmarkSuffixArrayBuild(b *testing.B) {
 	text := "The quick brown fox jumps over the lazy dog. " +
 		"Pack my box with five dozen liquor jugs. " +
@@ -102,11 +326,37 @@ func BenchmarkSuffixArraySearch(b *testing.B) {
 	}
 }
 
+func BenchmarkSuffixArraySearchFrequent(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	sa := NewSuffixArray(randomString(rng, 1<<20, "ab"))
+	
+	b.Run("Count", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sa.Count("abab")
+		}
+	})
+	
+	b.Run("Page", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sa.SearchWithOptions("abab", SearchOptions{Offset: 1000, Limit: 20})
+		}
+	})
+	
+	b.Run("SortedPage", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sa.SearchWithOptions("abab", SearchOptions{Sorted: true, Offset: 1000, Limit: 20})
+		}
+	})
+}
+
 func BenchmarkFMIndexBuild(b *testing.B) {
 	text := "The quick brown fox jumps over the lazy dog. " +
 		"Pack my box with five dozen liquor jugs. " +
 		"How vexingly quick daft zebras jump!"
 	
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		NewFMIndex(text, 4)
@@ -123,4 +373,24 @@ func BenchmarkFMIndexCount(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		fm.Count("quick")
 	}
+}
+
+func BenchmarkFMIndexLocate(b *testing.B) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100)
+	fm := NewFMIndex(text, 16)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fm.Locate("quick")
+	}
+}
+
+func BenchmarkFMIndexExtract(b *testing.B) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100)
+	fm := NewFMIndex(text, 32)
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		fm.Extract(1000, 80)
+	}
 }
\ No newline at end of file
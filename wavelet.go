@@ -0,0 +1,132 @@
+package fuzzy
+
+import "math/bits"
+
+// rankBitVector is a bit vector answering rank queries in constant time,
+// using one cumulative count per 512 bits on top of the bits themselves
+type rankBitVector struct {
+	words  []uint64
+	blocks []int // Ones before each run of blockWords words
+	n      int
+}
+
+const blockWords = 8
+
+func newRankBitVector(n int) *rankBitVector {
+	return &rankBitVector{
+		words: make([]uint64, (n+63)/64),
+		n:     n,
+	}
+}
+
+func (bv *rankBitVector) set(i int) {
+	bv.words[i/64] |= 1 << (i % 64)
+}
+
+func (bv *rankBitVector) get(i int) bool {
+	return bv.words[i/64]&(1<<(i%64)) != 0
+}
+
+// index builds the block counts once all bits are set
+func (bv *rankBitVector) index() {
+	bv.blocks = make([]int, len(bv.words)/blockWords+1)
+	ones := 0
+	for i, w := range bv.words {
+		ones += bits.OnesCount64(w)
+		if (i+1)%blockWords == 0 {
+			bv.blocks[(i+1)/blockWords] = ones
+		}
+	}
+}
+
+// rank1 returns the number of set bits in [0, i)
+func (bv *rankBitVector) rank1(i int) int {
+	w := i / 64
+	ones := bv.blocks[w/blockWords]
+	for j := w / blockWords * blockWords; j < w; j++ {
+		ones += bits.OnesCount64(bv.words[j])
+	}
+	if r := i % 64; r != 0 {
+		ones += bits.OnesCount64(bv.words[w] & (1<<r - 1))
+	}
+	return ones
+}
+
+// rank0 returns the number of clear bits in [0, i)
+func (bv *rankBitVector) rank0(i int) int {
+	return i - bv.rank1(i)
+}
+
+// waveletMatrix stores a byte sequence in 8 rank bit vectors, one per bit
+// from the most significant down, supporting access and rank in O(8)
+type waveletMatrix struct {
+	levels [8]*rankBitVector
+	zeros  [8]int // Number of clear bits in each level
+	n      int
+}
+
+func newWaveletMatrix(data []byte) *waveletMatrix {
+	wm := &waveletMatrix{n: len(data)}
+
+	cur := make([]byte, len(data))
+	next := make([]byte, len(data))
+	copy(cur, data)
+
+	for level := 0; level < 8; level++ {
+		bit := uint(7 - level)
+		bv := newRankBitVector(len(data))
+
+		// Stable partition: values with the bit clear move to the front
+		zeros := 0
+		for _, c := range cur {
+			if c>>bit&1 == 0 {
+				next[zeros] = c
+				zeros++
+			}
+		}
+		ones := zeros
+		for i, c := range cur {
+			if c>>bit&1 != 0 {
+				bv.set(i)
+				next[ones] = c
+				ones++
+			}
+		}
+
+		bv.index()
+		wm.levels[level] = bv
+		wm.zeros[level] = zeros
+		cur, next = next, cur
+	}
+
+	return wm
+}
+
+// access returns the byte at position i
+func (wm *waveletMatrix) access(i int) byte {
+	var c byte
+	for level, bv := range wm.levels {
+		if bv.get(i) {
+			c |= 1 << uint(7-level)
+			i = wm.zeros[level] + bv.rank1(i)
+		} else {
+			i = bv.rank0(i)
+		}
+	}
+	return c
+}
+
+// rank returns the number of occurrences of c in positions [0, i)
+func (wm *waveletMatrix) rank(c byte, i int) int {
+	s := 0
+	for level, bv := range wm.levels {
+		if c>>uint(7-level)&1 == 0 {
+			s = bv.rank0(s)
+			i = bv.rank0(i)
+		} else {
+			s = wm.zeros[level] + bv.rank1(s)
+			i = wm.zeros[level] + bv.rank1(i)
+		}
+	}
+	return i - s
+}
@@ -0,0 +1,63 @@
+package fuzzy
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRankBitVector(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 63, 64, 65, 511, 512, 513, 5000} {
+		bv := newRankBitVector(n)
+		want := make([]bool, n)
+		for i := range want {
+			if rng.Intn(3) == 0 {
+				want[i] = true
+				bv.set(i)
+			}
+		}
+		bv.index()
+
+		ones := 0
+		for i := 0; i <= n; i++ {
+			if got := bv.rank1(i); got != ones {
+				t.Fatalf("n=%d: rank1(%d) = %d, want %d", n, i, got, ones)
+			}
+			if i < n {
+				if bv.get(i) != want[i] {
+					t.Fatalf("n=%d: get(%d) = %v, want %v", n, i, bv.get(i), want[i])
+				}
+				if want[i] {
+					ones++
+				}
+			}
+		}
+	}
+}
+
+func TestWaveletMatrix(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := make([]byte, 3000)
+	for i := range data {
+		data[i] = byte(rng.Intn(256))
+		if rng.Intn(2) == 0 {
+			data[i] = "abc"[rng.Intn(3)]
+		}
+	}
+
+	wm := newWaveletMatrix(data)
+	var counts [256]int
+	for i := 0; i <= len(data); i++ {
+		for _, c := range []byte{0, 'a', 'b', 'c', 255, byte(i)} {
+			if got := wm.rank(c, i); got != counts[c] {
+				t.Fatalf("rank(%d, %d) = %d, want %d", c, i, got, counts[c])
+			}
+		}
+		if i < len(data) {
+			if got := wm.access(i); got != data[i] {
+				t.Fatalf("access(%d) = %d, want %d", i, got, data[i])
+			}
+			counts[data[i]]++
+		}
+	}
+}